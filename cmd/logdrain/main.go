@@ -0,0 +1,56 @@
+// logdrain ships app log entries from the redislog stream to a rotating local file.
+// It joins a consumer group, so several instances share the work and nothing is lost on restart:
+//
+//	go run ./cmd/logdrain -redis localhost:6379 -stream logs:stream -group shipper -out logs/drained.log
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"HelmyTask/utils/redislog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	host, _ := os.Hostname()
+	addr := flag.String("redis", "localhost:6379", "Redis address")
+	pass := flag.String("redis-password", os.Getenv("APP_REDIS_PASSWORD"), "Redis password")
+	db := flag.Int("redis-db", 0, "Redis logical DB")
+	stream := flag.String("stream", "logs:stream", "stream key written by the redis_stream sink")
+	group := flag.String("group", "shipper", "consumer group (created if missing)")
+	name := flag.String("consumer", host, "consumer name, unique per running instance")
+	out := flag.String("out", "logs/drained.log", "target file (JSON lines)")
+	maxSizeMB := flag.Int("max-size-mb", 100, "rotate the target file past this size")
+	maxBackups := flag.Int("max-backups", 10, "rotated files kept")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rdb := redis.NewClient(&redis.Options{Addr: *addr, Password: *pass, DB: *db})
+	defer rdb.Close()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		log.Fatalf("[logdrain] redis: %v", err)
+	}
+	file, err := redislog.NewFileSink(*out, int64(*maxSizeMB)<<20, *maxBackups)
+	if err != nil {
+		log.Fatalf("[logdrain] %v", err)
+	}
+
+	log.Printf("[logdrain] draining %s (group %s, consumer %s) into %s", *stream, *group, *name, *out)
+	c := redislog.NewConsumer(rdb, *stream, *group, *name)
+	err = c.Run(ctx, redislog.FileHandler(file)) // Returns nil on SIGINT/SIGTERM.
+	if cerr := file.Close(); cerr != nil {
+		log.Printf("[logdrain] close %s: %v", *out, cerr)
+	}
+	if err != nil {
+		log.Fatalf("[logdrain] %v", err)
+	}
+	log.Printf("[logdrain] stopped")
+}
@@ -6,6 +6,9 @@ import (
 	"log"
 
 	"HelmyTask/models" // Import our model(s) so we can auto-migrate schema.
+	"HelmyTask/repositories" // Per-statement timeout plugin.
+	"HelmyTask/utils/metrics" // Query latency histogram.
+	"HelmyTask/utils/tracing" // Query spans.
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -60,14 +63,46 @@ func InitDB(cfg *Config) *gorm.DB {
 		log.Fatalf("[db] connection error: %v", err)
 	}
 
-	
+	// Per-statement deadline; the request deadline still applies through WithContext(ctx).
+	if err := db.Use(repositories.QueryTimeout(MustDuration("db_query_timeout", cfg.DBQueryTimeout))); err != nil {
+		log.Fatalf("[db] query timeout plugin: %v", err)
+	}
+	if cfg.MetricsEnabled { // Time every statement into db_query_duration_seconds.
+		if err := db.Use(metrics.GormPlugin{}); err != nil {
+			log.Fatalf("[db] metrics plugin: %v", err)
+		}
+	}
+	if TracingEnabled(cfg) { // A client span per statement.
+		if err := db.Use(tracing.GormPlugin{}); err != nil {
+			log.Fatalf("[db] tracing plugin: %v", err)
+		}
+	}
+
 	// AutoMigrate creates or updates DB tables based on our struct definitions.
 	// Safe for demos/starters; for real projects you may use migrations.
 	// Migrate models (safe baseline)
-	if err := db.AutoMigrate(&models.User{}); err != nil {
+	hadVerifiedAt := db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt") // Checked before the column is added.
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.OneTimeToken{}); err != nil {
 		log.Fatalf("[db] automigrate error: %v", err)
 	}
+	if !hadVerifiedAt {
+		backfillEmailVerified(db)
+	}
 
 	return db // Return the connected *gorm.DB to be injected into repositories.
 
 }
+
+// backfillEmailVerified runs once, when email_verified_at is first added: accounts that existed
+// before verification was introduced count as verified, so require_verified_email does not lock them out.
+func backfillEmailVerified(db *gorm.DB) {
+	res := db.Model(&models.User{}).Unscoped(). // Soft-deleted rows too; they may be restored.
+		Where("email_verified_at IS NULL").
+		UpdateColumn("email_verified_at", gorm.Expr("created_at"))
+	if res.Error != nil {
+		log.Fatalf("[db] backfill email_verified_at: %v", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("[db] marked %d existing users as email-verified", res.RowsAffected)
+	}
+}
@@ -0,0 +1,29 @@
+//builds the *http.Server with the configured timeouts (instead of gin's r.Run, which has none).
+
+package config
+
+import (
+	"net/http"
+)
+
+// InitHTTPServer wraps handler in an http.Server listening on http_port.
+func InitHTTPServer(cfg *Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.HTTPPort,
+		Handler:           handler,
+		ReadTimeout:       MustDuration("http_read_timeout", cfg.HTTPReadTimeout),
+		ReadHeaderTimeout: MustDuration("http_read_header_timeout", cfg.HTTPReadHeaderTimeout),
+		WriteTimeout:      MustDuration("http_write_timeout", cfg.HTTPWriteTimeout),
+		IdleTimeout:       MustDuration("http_idle_timeout", cfg.HTTPIdleTimeout),
+	}
+}
+
+// InitMetricsServer wraps handler in the dedicated metrics listener (metrics_addr).
+func InitMetricsServer(cfg *Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.MetricsAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: MustDuration("http_read_header_timeout", cfg.HTTPReadHeaderTimeout),
+		IdleTimeout:       MustDuration("http_idle_timeout", cfg.HTTPIdleTimeout),
+	}
+}
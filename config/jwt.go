@@ -0,0 +1,116 @@
+//builds the JWT key set from config: HS256 with jwt_secret, or PEM keys for RS256/ES256/EdDSA.
+
+package config
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"HelmyTask/utils/jwtauth"
+)
+
+// JWTKey is one entry of jwt_keys in config.yaml.
+// A key with private_key_file can sign; a key with only public_key_file is verify-only (rotating out).
+type JWTKey struct {
+	KID            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`              // defaults to jwt_alg
+	PrivateKeyFile string `mapstructure:"private_key_file"` // PEM (PKCS1/PKCS8/SEC1)
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // PEM (PKIX)
+}
+
+// JWTSecretKey is one entry of jwt_verify_secrets: a retired HS256 secret, accepted for verification only.
+type JWTSecretKey struct {
+	KID    string `mapstructure:"kid"` // kid the old tokens carry ("" = none)
+	Secret string `mapstructure:"secret"`
+}
+
+// InitJWTKeys loads the signing/verification keys; fails fast on bad key material.
+// Retired HS256 secrets stay verify-only so switching algorithms or rotating the secret logs nobody out.
+func InitJWTKeys(cfg *Config) *jwtauth.KeySet {
+	if cfg.JWTAlg == "" || cfg.JWTAlg == "HS256" { // Shared-secret mode (default).
+		if cfg.JWTSecret == "" {
+			log.Fatal("[jwt] HS256 selected but jwt_secret empty")
+		}
+		ks, err := jwtauth.NewKeySet(jwtauth.NewHMACKey(cfg.JWTActiveKID, cfg.JWTSecret), retiredHMACKeys(cfg, "")...)
+		if err != nil {
+			log.Fatalf("[jwt] %v (give the new secret its own jwt_active_kid)", err)
+		}
+		return ks
+	}
+
+	var (
+		active *jwtauth.Key   // signs new tokens
+		others []*jwtauth.Key // still accepted for verification
+	)
+	for _, kc := range cfg.JWTKeys {
+		alg := kc.Alg
+		if alg == "" {
+			alg = cfg.JWTAlg
+		}
+		k, err := jwtauth.LoadKey(kc.KID, alg, kc.PrivateKeyFile, kc.PublicKeyFile)
+		if err != nil {
+			log.Fatalf("[jwt] %v", err)
+		}
+		// Active key: the configured kid, else the first key able to sign.
+		if active == nil && k.CanSign() && (cfg.JWTActiveKID == "" || cfg.JWTActiveKID == k.KID) {
+			active = k
+			continue
+		}
+		others = append(others, k)
+	}
+	if active == nil {
+		log.Fatalf("[jwt] no signing key found for jwt_alg=%s jwt_active_kid=%q", cfg.JWTAlg, cfg.JWTActiveKID)
+	}
+	others = append(others, retiredHMACKeys(cfg, cfg.JWTSecret)...)
+	ks, err := jwtauth.NewKeySet(active, others...)
+	if err != nil {
+		log.Fatalf("[jwt] %v", err)
+	}
+	log.Printf("[jwt] %s signing with kid=%s (%d verify-only keys)", active.Method.Alg(), active.KID, len(others))
+	return ks
+}
+
+// retiredHMACKeys returns the verify-only HS256 keys: jwt_verify_secrets plus, after a switch to
+// asymmetric signing, the former jwt_secret (tokens minted with it carry no kid unless listed there).
+// They are skipped when jwt_allowed_algs is set without HS256, since those tokens would be refused anyway.
+func retiredHMACKeys(cfg *Config, formerSecret string) []*jwtauth.Key {
+	if len(cfg.JWTAllowedAlgs) > 0 && !slices.Contains(cfg.JWTAllowedAlgs, "HS256") {
+		if formerSecret != "" || len(cfg.JWTVerifySecrets) > 0 {
+			log.Printf("[jwt] jwt_allowed_algs excludes HS256; tokens signed with retired secrets are no longer accepted")
+		}
+		return nil
+	}
+	var keys []*jwtauth.Key
+	kids := map[string]bool{}
+	for _, vs := range cfg.JWTVerifySecrets {
+		if vs.Secret == "" {
+			log.Fatalf("[jwt] jwt_verify_secrets entry kid=%q has no secret", vs.KID)
+		}
+		keys = append(keys, jwtauth.NewHMACKey(vs.KID, vs.Secret))
+		kids[vs.KID] = true
+	}
+	if strings.Contains(formerSecret, "${") { // Unexpanded placeholder: a publicly known string, never a key.
+		formerSecret = ""
+	}
+	if formerSecret != "" && !kids[""] {
+		keys = append(keys, jwtauth.NewHMACKey("", formerSecret))
+	}
+	return keys
+}
+
+// InitTokenValidator wraps the key set with the iss/aud/leeway/alg policy from config.
+// The same value signs (service) and verifies (middleware), so the two cannot disagree.
+func InitTokenValidator(cfg *Config, keys *jwtauth.KeySet) *jwtauth.Validator {
+	leeway, err := time.ParseDuration(cfg.JWTLeeway)
+	if err != nil {
+		log.Fatalf("[config] invalid jwt_leeway value: %v", err)
+	}
+	return jwtauth.NewValidator(keys, jwtauth.ValidatorConfig{
+		Issuer:     cfg.JWTIssuer,
+		Audience:   cfg.JWTAudience,
+		Leeway:     leeway,
+		Algorithms: cfg.JWTAllowedAlgs,
+	})
+}
@@ -0,0 +1,26 @@
+//sets up the process-wide slog logger from config (log_format text|json, log_level).
+
+package config
+
+import (
+	"log"
+	"log/slog"
+	"os"
+
+	"HelmyTask/utils/logging"
+)
+
+// InitLogger installs the slog default logger. Plain log.Printf calls are routed through it too,
+// so every line shares one format. Fails fast on an unknown level or format.
+func InitLogger(cfg *Config) *slog.Logger {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	l, err := logging.New(os.Stderr, cfg.LogFormat, level)
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	slog.SetDefault(l)
+	return l
+}
@@ -0,0 +1,37 @@
+//builds the request rate limits from config (Redis sliding window, in-memory fallback).
+
+package config
+
+import (
+	"log"
+
+	"HelmyTask/middlewares"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// InitRateLimits parses the rate_limit_* settings; fails fast on malformed rates.
+// With rdb nil the counters are kept per process.
+func InitRateLimits(cfg *Config, rdb *redis.Client) middlewares.RateLimits {
+	if !cfg.RateLimitEnabled {
+		return middlewares.RateLimits{}
+	}
+	authLimit, authWindow, err := middlewares.ParseRate(cfg.RateLimitAuth)
+	if err != nil {
+		log.Fatalf("[config] invalid rate_limit_auth: %v", err)
+	}
+	apiLimit, apiWindow, err := middlewares.ParseRate(cfg.RateLimitAPI)
+	if err != nil {
+		log.Fatalf("[config] invalid rate_limit_api: %v", err)
+	}
+	switch cfg.RateLimitKeyBy {
+	case middlewares.RateKeyIP, middlewares.RateKeyUser, middlewares.RateKeyAPIKey:
+	default:
+		log.Fatalf("[config] invalid rate_limit_key_by %q (ip|user|api_key)", cfg.RateLimitKeyBy)
+	}
+	return middlewares.RateLimits{
+		Limiter: middlewares.NewRedisLimiter(rdb),
+		Auth:    middlewares.RateLimitRule{Name: "auth", Limit: authLimit, Window: authWindow, KeyBy: middlewares.RateKeyIP},
+		API:     middlewares.RateLimitRule{Name: "api", Limit: apiLimit, Window: apiWindow, KeyBy: cfg.RateLimitKeyBy},
+	}
+}
@@ -5,19 +5,27 @@ import (
 	"log"
 	"time"
 
+	"HelmyTask/utils/tracing"
+
 	"github.com/redis/go-redis/v9"
 )
 
 // InitRedis creates a single Redis client and verifies connectivity with Ping.
 // It also configures sane timeouts so the app fails fast if Redis is unreachable.
+// An empty redis_addr disables Redis and returns nil (cache off, DB-backed token storage).
 func InitRedis(cfg *Config) *redis.Client {
+	if cfg.RedisAddr == "" {
+		log.Printf("[redis] disabled (redis_addr empty)")
+		return nil
+	}
 	opts := &redis.Options{
 		Addr:        cfg.RedisAddr,
 		Password:    cfg.RedisPass,
 		DB:          cfg.RedisDB,
 		DialTimeout: 3 * time.Second,
-		ReadTimeout: 2 * time.Second,
-		WriteTimeout: 2 * time.Second,
+		ReadTimeout: MustDuration("redis_timeout", cfg.RedisTimeout),
+		WriteTimeout: MustDuration("redis_timeout", cfg.RedisTimeout),
+		ContextTimeoutEnabled: true, // Also honour the caller's (request) deadline.
 		PoolSize:     10,
 		MinIdleConns: 2,
 	}
@@ -28,5 +36,8 @@ func InitRedis(cfg *Config) *redis.Client {
 		log.Fatalf("[redis] ping failed: %v (addr=%s db=%d)", err, cfg.RedisAddr, cfg.RedisDB)
 	}
 	log.Printf("[redis] connected: addr=%s db=%d", cfg.RedisAddr, cfg.RedisDB)
+	if TracingEnabled(cfg) {
+		tracing.InstrumentRedis(rdb) // After Ping so the startup check is not traced.
+	}
 	return rdb
 }
@@ -0,0 +1,137 @@
+//builds the app log writer from config (sinks, queue size, batching, overflow policy).
+
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"HelmyTask/utils/redislog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLogSink is one entry of redislog_sinks in config.yaml.
+type RedisLogSink struct {
+	Type       string   `mapstructure:"type"`        // redis_list|redis_stream|file|stdout
+	MinLevel   string   `mapstructure:"min_level"`   // info|warn|error; lower levels skip this sink (default info)
+	Key        string   `mapstructure:"key"`         // redis_list/redis_stream key (default logs:app / logs:stream)
+	MaxLen     int64    `mapstructure:"max_len"`     // entries kept (default 1000 for a list, 100000 for a stream)
+	Groups     []string `mapstructure:"groups"`      // redis_stream: consumer groups created at startup (log shippers)
+	Retention  string   `mapstructure:"retention"`   // redis_list key expiry (default 168h; "0" = never)
+	Path       string   `mapstructure:"path"`        // file: target file (default logs/app.log)
+	MaxSizeMB  int      `mapstructure:"max_size_mb"` // file: rotate past this size (default 10)
+	MaxBackups int      `mapstructure:"max_backups"` // file: rotated files kept (default 5)
+}
+
+// InitRedisLog returns the app logger fanning out to redislog_sinks; fails fast on bad sink settings
+// and on unredacted logging outside env=dev.
+// Redis sinks are skipped while Redis is disabled; with nothing left it logs to stdout instead of nowhere.
+func InitRedisLog(cfg *Config, rdb *redis.Client) *redislog.Logger {
+	overflow, err := redislog.ParseOverflow(cfg.RedisLogOverflow)
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	opts := []redislog.Option{
+		redislog.WithBufferSize(cfg.RedisLogBuffer),
+		redislog.WithBatchSize(cfg.RedisLogBatch),
+		redislog.WithFlushInterval(MustDuration("redislog_flush_interval", cfg.RedisLogFlushInterval)),
+		redislog.WithOverflow(overflow),
+		redislog.WithRedactor(newRedactor(cfg)),
+	}
+	sinks := 0
+	for i, sc := range cfg.RedisLogSinks {
+		level, err := redislog.ParseLevel(sc.MinLevel)
+		if err != nil {
+			log.Fatalf("[config] redislog_sinks[%d]: %v", i, err)
+		}
+		sink := newRedisLogSink(i, sc, rdb)
+		if sink == nil {
+			continue
+		}
+		opts = append(opts, redislog.WithSink(sink, level))
+		sinks++
+	}
+	if sinks == 0 {
+		log.Printf("[config] no usable redislog sink, app logs go to stdout")
+		opts = append(opts, redislog.WithSink(redislog.NewStdoutSink(), ""))
+	}
+	return redislog.New(rdb, "logs:app", 1000, 7*24*time.Hour, opts...)
+}
+
+// newRedactor builds the PII redaction applied to every entry.
+func newRedactor(cfg *Config) *redislog.Redactor {
+	mode, err := redislog.ParseRedactMode(cfg.RedisLogRedact)
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	if mode == redislog.RedactOff && cfg.Env != "dev" {
+		log.Fatalf("[config] redislog_redact=off is only allowed with env=dev (env=%s)", cfg.Env)
+	}
+	secret := []byte(cfg.RedisLogRedactSecret)
+	if len(secret) == 0 { // Own key, never the JWT signing secret itself.
+		secret = deriveKey(cfg.JWTSecret, "redislog-redact")
+	}
+	if mode == redislog.RedactHash && len(secret) == 0 {
+		log.Fatal("[config] redislog_redact=hash needs redislog_redact_secret (or jwt_secret)")
+	}
+	keys := append(append([]string{}, redislog.DefaultRedactKeys...), cfg.RedisLogRedactKeys...)
+	return redislog.NewRedactor(mode, keys, secret)
+}
+
+// newRedisLogSink builds one configured sink; nil means it was skipped (Redis disabled).
+func newRedisLogSink(i int, sc RedisLogSink, rdb *redis.Client) redislog.Sink {
+	switch sc.Type {
+	case "redis_list", "redis_stream":
+		if rdb == nil {
+			log.Printf("[config] redislog_sinks[%d]: %s skipped, redis disabled", i, sc.Type)
+			return nil
+		}
+		if sc.Type == "redis_stream" {
+			key := orDefault(sc.Key, "logs:stream")
+			for _, g := range sc.Groups { // Exist before the first entry, so shippers miss nothing.
+				if err := redislog.EnsureGroup(context.Background(), rdb, key, g); err != nil {
+					log.Fatalf("[config] redislog_sinks[%d]: group %q: %v", i, g, err)
+				}
+			}
+			return redislog.NewStreamSink(rdb, key, int64OrDefault(sc.MaxLen, 100000))
+		}
+		retention := 7 * 24 * time.Hour
+		if sc.Retention != "" {
+			retention = MustDuration("redislog_sinks.retention", sc.Retention)
+		}
+		return redislog.NewListSink(rdb, orDefault(sc.Key, "logs:app"), int64OrDefault(sc.MaxLen, 1000), retention)
+	case "file":
+		size, backups := sc.MaxSizeMB, sc.MaxBackups
+		if size == 0 {
+			size = 10
+		}
+		if backups == 0 {
+			backups = 5
+		}
+		s, err := redislog.NewFileSink(orDefault(sc.Path, "logs/app.log"), int64(size)<<20, backups)
+		if err != nil {
+			log.Fatalf("[config] redislog_sinks[%d]: %v", i, err)
+		}
+		return s
+	case "stdout":
+		return redislog.NewStdoutSink()
+	}
+	log.Fatalf("[config] redislog_sinks[%d]: unknown type %q (want redis_list|redis_stream|file|stdout)", i, sc.Type)
+	return nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func int64OrDefault(n, def int64) int64 {
+	if n == 0 {
+		return def
+	}
+	return n
+}
@@ -3,6 +3,8 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"log"
 	"strings"
 	"time"
@@ -18,8 +20,49 @@ type Config struct {
 	AppName    string `mapstructure:"app_name"`
 	Env        string `mapstructure:"env"`         // dev|staging|prod
 	HTTPPort   string `mapstructure:"http_port"`   // "8080"
+
+	// HTTP server timeouts and graceful shutdown (durations, e.g. "10s").
+	HTTPReadTimeout       string `mapstructure:"http_read_timeout"`        // whole request incl. body
+	HTTPReadHeaderTimeout string `mapstructure:"http_read_header_timeout"` // headers only (slowloris guard)
+	HTTPWriteTimeout      string `mapstructure:"http_write_timeout"`       // response write
+	HTTPIdleTimeout       string `mapstructure:"http_idle_timeout"`        // keep-alive idle connections
+	ShutdownTimeout       string `mapstructure:"shutdown_timeout"`         // max time to drain in-flight requests
+	ShutdownDrainDelay    string `mapstructure:"shutdown_drain_delay"`     // /readyz fails this long before the listener closes
+
+	LogLevel  string `mapstructure:"log_level"`  // debug|info|warn|error
+	LogFormat string `mapstructure:"log_format"` // text|json
+
+	// App log (redislog) background writer and its sinks.
+	RedisLogBuffer        int            `mapstructure:"redislog_buffer"`         // queued entries; 0 = write inline
+	RedisLogBatch         int            `mapstructure:"redislog_batch"`          // entries per sink write
+	RedisLogFlushInterval string         `mapstructure:"redislog_flush_interval"` // max wait for a partial batch
+	RedisLogOverflow      string         `mapstructure:"redislog_overflow"`       // drop|block when the queue is full
+	RedisLogSinks         []RedisLogSink `mapstructure:"redislog_sinks"`          // where entries go (fan-out)
+	RedisLogRedact        string         `mapstructure:"redislog_redact"`         // mask|hash|off (off only with env=dev)
+	RedisLogRedactKeys    []string       `mapstructure:"redislog_redact_keys"`    // sensitive meta keys on top of email/token/password/ip
+	RedisLogRedactSecret  string         `mapstructure:"redislog_redact_secret"`  // keys hash mode; empty derives a key from jwt_secret
+
+	// Deadlines carried by the request context down to DB/Redis ("0" disables).
+	RequestTimeout string `mapstructure:"request_timeout"`  // whole handler incl. all DB/Redis calls → 504
+	DBQueryTimeout string `mapstructure:"db_query_timeout"` // each SQL statement
+	RedisTimeout   string `mapstructure:"redis_timeout"`    // each Redis read/write
+
 	JWTSecret  string `mapstructure:"jwt_secret"`  // strong secret
-	JWTExpires string `mapstructure:"jwt_expires"` // Token lifetime parsed by time.ParseDuration, e.g., "72h".
+	JWTExpires string `mapstructure:"jwt_expires"` // Access token lifetime parsed by time.ParseDuration, e.g., "15m".
+	RefreshExpires string `mapstructure:"refresh_expires"` // Refresh token lifetime, e.g., "720h".
+
+	// Asymmetric signing (optional). With jwt_alg != HS256 keys come from jwt_keys; jwt_secret then only verifies old tokens.
+	JWTAlg       string   `mapstructure:"jwt_alg"`        // HS256|RS256|ES256|EdDSA
+	JWTActiveKID string   `mapstructure:"jwt_active_kid"` // kid that signs new tokens
+	JWTKeys      []JWTKey `mapstructure:"jwt_keys"`       // signing + verify-only keys (rotation)
+	// Retired HS256 secrets accepted for verification only (secret rotation or a switch away from HS256).
+	JWTVerifySecrets []JWTSecretKey `mapstructure:"jwt_verify_secrets"`
+
+	// Claim policy shared by issuance and verification.
+	JWTIssuer      string   `mapstructure:"jwt_issuer"`       // "iss" stamped and required
+	JWTAudience    string   `mapstructure:"jwt_audience"`     // "aud" stamped and required
+	JWTLeeway      string   `mapstructure:"jwt_leeway"`       // clock skew tolerance, e.g., "30s"
+	JWTAllowedAlgs []string `mapstructure:"jwt_allowed_algs"` // accepted "alg" values; empty = algs of jwt keys
 
 	//JWTExpires time.Duration `mapstructure:"jwt_expires"`   // "72h" X X X X X X X X X X X 
 
@@ -34,13 +77,65 @@ type Config struct {
 	//
 	//
 
-	RedisAddr string `mapstructure:"redis_addr"`     // "localhost:6379" // Host:port for Redis server.
+	// Outgoing mail and password reset.
+	Mailer        string `mapstructure:"mailer"`          // log|file
+	MailerFile    string `mapstructure:"mailer_file"`     // path used by the file mailer
+	ResetExpires  string `mapstructure:"reset_expires"`   // password reset token lifetime, e.g., "30m"
+
+	// Email verification.
+	RequireVerifiedEmail bool   `mapstructure:"require_verified_email"` // block login until verified
+	VerifyExpires        string `mapstructure:"verify_expires"`         // verification token lifetime, e.g., "24h"
+	VerifyResendInterval string `mapstructure:"verify_resend_interval"` // min gap between resends, e.g., "1m"
+
+	// Bootstrap admin: created (or promoted) at startup when admin_email is set.
+	AdminName     string `mapstructure:"admin_name"`
+	AdminEmail    string `mapstructure:"admin_email"`
+	AdminPassword string `mapstructure:"admin_password"` // only used when the account does not exist yet
+
+	// Login brute-force protection (needs Redis).
+	LockoutMaxAccountFailures int    `mapstructure:"lockout_max_account_failures"` // failures per account before a lock (0 disables)
+	LockoutMaxIPFailures      int    `mapstructure:"lockout_max_ip_failures"`      // failures per client IP before a lock (0 disables)
+	LockoutWindow             string `mapstructure:"lockout_window"`               // failures are counted within this window
+	LockoutBase               string `mapstructure:"lockout_base"`                 // first lock duration; doubles on every further lock
+	LockoutMax                string `mapstructure:"lockout_max"`                  // cap for the doubling
+
+	// Request throttling ("<limit>/<window>", empty disables a group).
+	RateLimitEnabled bool   `mapstructure:"rate_limit_enabled"`
+	RateLimitAuth    string `mapstructure:"rate_limit_auth"`   // public /auth/* per IP, e.g. "20/1m"
+	RateLimitAPI     string `mapstructure:"rate_limit_api"`    // authenticated routes, e.g. "300/1m"
+	RateLimitKeyBy   string `mapstructure:"rate_limit_key_by"` // ip|user|api_key for authenticated routes
+
+	// List cursors are HMAC-signed; share this across instances. Empty derives a key from jwt_secret.
+	CursorSecret string `mapstructure:"cursor_secret"`
+
+	// Soft-deleted users are purged for good after the retention period ("0" disables purging).
+	UserPurgeRetention string `mapstructure:"user_purge_retention"` // e.g. "720h"
+	UserPurgeInterval  string `mapstructure:"user_purge_interval"`  // how often the purge runs, e.g. "1h"
+
+	// Prometheus metrics. With metrics_addr set they are served on that address only, not on http_port.
+	MetricsEnabled bool   `mapstructure:"metrics_enabled"`
+	MetricsPath    string `mapstructure:"metrics_path"`  // e.g. "/metrics"
+	MetricsAddr    string `mapstructure:"metrics_addr"`  // e.g. ":9090"; empty = same server as the API
+	MetricsToken   string `mapstructure:"metrics_token"` // bearer token required to scrape (empty = open)
+
+	// OpenTelemetry tracing.
+	TracingExporter    string  `mapstructure:"tracing_exporter"`     // none|stdout|otlp
+	TracingEndpoint    string  `mapstructure:"tracing_endpoint"`     // OTLP/HTTP URL, e.g. "http://localhost:4318"
+	TracingSampleRatio float64 `mapstructure:"tracing_sample_ratio"` // 0..1 share of new traces recorded
+
+	RedisAddr string `mapstructure:"redis_addr"`     // "localhost:6379" // Host:port for Redis server; empty disables Redis.
 	RedisDB   int    `mapstructure:"redis_db"`       // Redis logical DB number
 	RedisPass string `mapstructure:"redis_password"` // Redis password (if any)
 }
 
-// expose parsed duration globally
-var JWTExpiryDuration time.Duration
+// expose parsed durations globally
+var (
+	JWTExpiryDuration     time.Duration
+	RefreshExpiryDuration time.Duration
+	ResetExpiryDuration   time.Duration
+	VerifyExpiryDuration  time.Duration
+	VerifyResendDuration  time.Duration
+)
 
 func Load() *Config {
 	v := viper.New()                                   // Create a new Viper instance (isolated, not global).
@@ -56,11 +151,59 @@ func Load() *Config {
 	v.SetDefault("app_name", "HelmyTask")        // Default app name.
 	v.SetDefault("env", "dev")                   // Default environment.
 	v.SetDefault("http_port", "8080")            //default http portt
-	v.SetDefault("jwt_expires", "72h")           // default jwt lifetime
+	v.SetDefault("http_read_timeout", "15s")     // Slow clients cannot hold a connection forever.
+	v.SetDefault("http_read_header_timeout", "5s")
+	v.SetDefault("http_write_timeout", "30s")
+	v.SetDefault("http_idle_timeout", "120s")
+	v.SetDefault("shutdown_timeout", "20s")      // Drain deadline on SIGTERM/SIGINT.
+	v.SetDefault("shutdown_drain_delay", "0s")   // No load balancer to tell in dev.
+	v.SetDefault("log_level", "info")            // Hide debug output unless asked for.
+	v.SetDefault("log_format", "text")           // Human-readable; use json in production.
+	v.SetDefault("redislog_buffer", 1024)        // Room for bursts before the overflow policy applies.
+	v.SetDefault("redislog_batch", 100)          // One round trip per 100 entries...
+	v.SetDefault("redislog_flush_interval", "500ms") // ...or per half second, whichever comes first.
+	v.SetDefault("redislog_overflow", "drop")    // Never let logging slow a request down.
+	v.SetDefault("redislog_sinks", []map[string]any{{"type": "redis_list"}}) // logs:app, as before.
+	v.SetDefault("redislog_redact", "mask")      // No raw emails/IPs/tokens in stored logs.
+	v.SetDefault("redislog_redact_secret", "")   // Declared so APP_REDISLOG_REDACT_SECRET is picked up.
+	v.SetDefault("request_timeout", "10s")       // Give up on a request (504) after this long.
+	v.SetDefault("db_query_timeout", "5s")       // One slow query cannot eat the whole request budget.
+	v.SetDefault("redis_timeout", "2s")          // Cache/lockout calls should be fast or skipped.
+	v.SetDefault("jwt_alg", "HS256")             // shared-secret signing unless configured otherwise
+	v.SetDefault("jwt_issuer", "HelmyTask")      // iss claim
+	v.SetDefault("jwt_audience", "HelmyTask-api") // aud claim
+	v.SetDefault("jwt_leeway", "30s")            // tolerated clock skew
+	v.SetDefault("jwt_expires", "15m")           // default jwt lifetime (short; clients use refresh tokens)
+	v.SetDefault("refresh_expires", "720h")      // default refresh token lifetime (30 days)
 	v.SetDefault("db_driver", "mysql")           //default to MySql(can be also : postgres | sqlite || sqlserver)
 	v.SetDefault("sqlite_path", "app.db")        //// Default sqlite file path if sqlite is used.
 	v.SetDefault("redis_addr", "localhost:6379") // Default Redis address.
 	v.SetDefault("redis_db", 0)                  // Use Redis DB 0 by default.
+	v.SetDefault("admin_name", "admin")          // Name for a freshly created bootstrap admin.
+	v.SetDefault("mailer", "log")                // Print emails to the log in dev.
+	v.SetDefault("mailer_file", "mail.log")      // Used when mailer=file.
+	v.SetDefault("reset_expires", "30m")         // Password reset token lifetime.
+	v.SetDefault("require_verified_email", false) // Allow unverified logins unless enabled.
+	v.SetDefault("verify_expires", "24h")        // Verification token lifetime.
+	v.SetDefault("verify_resend_interval", "1m") // Resend throttle.
+	v.SetDefault("lockout_max_account_failures", 5) // Lock an account after 5 bad passwords...
+	v.SetDefault("lockout_max_ip_failures", 20)  // ...or an IP after 20 (any account).
+	v.SetDefault("lockout_window", "15m")        // Counting window.
+	v.SetDefault("lockout_base", "1m")           // First lock; 2m, 4m, ... afterwards.
+	v.SetDefault("lockout_max", "1h")            // Longest lock.
+	v.SetDefault("rate_limit_enabled", true)     // Throttle requests.
+	v.SetDefault("rate_limit_auth", "20/1m")     // Strict on login/register/reset.
+	v.SetDefault("rate_limit_api", "300/1m")     // Generous for normal API use.
+	v.SetDefault("rate_limit_key_by", "user")    // Count authenticated calls per user.
+	v.SetDefault("user_purge_retention", "720h") // Keep deleted users restorable for 30 days.
+	v.SetDefault("user_purge_interval", "1h")    // Purge check frequency.
+	v.SetDefault("metrics_enabled", true)        // Expose Prometheus metrics...
+	v.SetDefault("metrics_path", "/metrics")     // ...at this path on the API port (see metrics_addr).
+	v.SetDefault("metrics_addr", "")             // Declared so APP_METRICS_ADDR is picked up.
+	v.SetDefault("metrics_token", "")            // Declared so APP_METRICS_TOKEN is picked up.
+	v.SetDefault("tracing_exporter", "none")     // Tracing off unless an exporter is chosen.
+	v.SetDefault("tracing_endpoint", "")         // Empty = OTEL_EXPORTER_OTLP_* env or localhost:4318.
+	v.SetDefault("tracing_sample_ratio", 1.0)    // Record every trace.
 
 	// Try to read config file; if not found, proceed with defaults + env vars.
 
@@ -84,6 +227,59 @@ func Load() *Config {
 	}
 	JWTExpiryDuration = d
 
+	// parse refresh_expires the same way
+	rd, err := time.ParseDuration(c.RefreshExpires)
+	if err != nil {
+		log.Fatalf("[config] invalid refresh_expires value: %v", err)
+	}
+	RefreshExpiryDuration = rd
+
+	// parse reset_expires
+	if ResetExpiryDuration, err = time.ParseDuration(c.ResetExpires); err != nil {
+		log.Fatalf("[config] invalid reset_expires value: %v", err)
+	}
+	// parse verification durations
+	if VerifyExpiryDuration, err = time.ParseDuration(c.VerifyExpires); err != nil {
+		log.Fatalf("[config] invalid verify_expires value: %v", err)
+	}
+	if VerifyResendDuration, err = time.ParseDuration(c.VerifyResendInterval); err != nil {
+		log.Fatalf("[config] invalid verify_resend_interval value: %v", err)
+	}
+
+	// Metrics on the public API port would be open to anyone in prod; demand a scrape token there.
+	if c.MetricsEnabled && c.MetricsAddr == "" && c.MetricsToken == "" && c.Env == "prod" {
+		log.Fatal("[config] metrics on the API port need metrics_token (APP_METRICS_TOKEN) in prod; or set metrics_addr / metrics_enabled=false")
+	}
+
 	return &c // Return a pointer so caller shares the same object.
 
 }
+
+// MustDuration parses a duration setting or stops the process naming the bad key.
+func MustDuration(key, val string) time.Duration {
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Fatalf("[config] invalid %s value: %v", key, err)
+	}
+	return d
+}
+
+// CursorKey returns the key that signs list cursors: cursor_secret, or else a key derived
+// from jwt_secret so a leaked cursor key cannot forge tokens. Nil when both are empty.
+func CursorKey(c *Config) []byte {
+	if c.CursorSecret != "" {
+		return []byte(c.CursorSecret)
+	}
+	return deriveKey(c.JWTSecret, "cursor")
+}
+
+// deriveKey turns jwt_secret into a per-purpose key (HMAC-SHA256 of the purpose label),
+// so features that fall back to it never hold the signing secret itself. Nil for an empty secret.
+func deriveKey(secret, purpose string) []byte {
+	if secret == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,36 @@
+//sets up OpenTelemetry tracing from config (exporter otlp|stdout|none).
+
+package config
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"HelmyTask/utils/tracing"
+)
+
+// InitTracing installs the tracer provider; the returned func flushes spans on shutdown.
+// Fails fast on an unknown exporter.
+func InitTracing(ctx context.Context, cfg *Config) func(context.Context) error {
+	shutdown, err := tracing.Init(ctx, tracing.Config{
+		ServiceName: cfg.AppName,
+		Environment: cfg.Env,
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	if TracingEnabled(cfg) {
+		log.Printf("[tracing] exporter=%s sample_ratio=%.2f", cfg.TracingExporter, cfg.TracingSampleRatio)
+	}
+	return shutdown
+}
+
+// TracingEnabled reports whether spans are exported (instrumentation is skipped otherwise).
+func TracingEnabled(cfg *Config) bool {
+	e := strings.ToLower(cfg.TracingExporter)
+	return e != "" && e != tracing.ExporterNone
+}
@@ -8,4 +8,17 @@ const (
 	// Using a string constant reduces risk of typos and collisions.
 	
 	CtxUserIDKey = "uid"
+	CtxRoleKey   = "role" // role claim of the authenticated user
+
+	// Gin context keys for the token ID (jti) and expiry of the presented JWT (used by logout).
+	CtxTokenIDKey  = "jti"
+	CtxTokenExpKey = "token_exp"
+)
+
+// Build metadata, stamped at build time:
+//
+//	go build -ldflags "-X HelmyTask/global.GitCommit=$(git rev-parse --short HEAD) -X HelmyTask/global.BuildTime=$(date -u +%FT%TZ)"
+var (
+	GitCommit = "unknown"
+	BuildTime = "unknown"
 )
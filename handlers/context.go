@@ -0,0 +1,32 @@
+package handlers // Controller layer translates HTTP <-> service calls.
+
+import ( // Imports for context error mapping.
+	"context"  // Deadline/cancel sentinels.
+	"errors"   // errors.Is.
+	"net/http" // Status codes.
+
+	"github.com/gin-gonic/gin" // Gin web framework.
+)
+
+// statusClientClosedRequest is nginx's non-standard 499: the client went away before the answer.
+const statusClientClosedRequest = 499
+
+// contextDone answers for errors caused by a deadline (request_timeout, db_query_timeout) or a client
+// that went away, and reports whether it did. Without it a timed-out lookup would surface as a misleading 404/401.
+func contextDone(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if cause := c.Request.Context().Err(); cause != nil { // Services may replace it (e.g. "invalid refresh token").
+		err = cause
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out"})
+	case errors.Is(err, context.Canceled):
+		c.AbortWithStatus(statusClientClosedRequest) // Nobody is listening; skip the body.
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package handlers // Controller layer translates HTTP <-> service calls.
+
+import ( // Imports for health endpoints.
+	"context" // Per-check timeouts.
+	"errors" // Disabled sentinel.
+	"net/http" // Status codes.
+	"runtime" // Go version.
+	"sync/atomic" // Shutdown flag read by concurrent requests.
+	"time" // Latency + uptime.
+
+	"HelmyTask/global" // Version and build info.
+
+	"github.com/gin-gonic/gin" // Gin web framework.
+	"github.com/redis/go-redis/v9" // Redis ping.
+	"gorm.io/gorm" // DB pool ping.
+)
+
+// healthCheckTimeout bounds each dependency ping so /readyz answers quickly even when one hangs.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthHandler serves liveness and readiness probes.
+type HealthHandler struct {
+	db       *gorm.DB // Hard dependency.
+	rdb      *redis.Client // Hard dependency when configured (token stores live there); nil = disabled.
+	started  time.Time // For uptime.
+	draining atomic.Bool // Set on shutdown so load balancers stop routing here first.
+}
+
+// NewHealthHandler wires the dependencies that /readyz checks.
+func NewHealthHandler(db *gorm.DB, rdb *redis.Client) *HealthHandler {
+	return &HealthHandler{db: db, rdb: rdb, started: time.Now()}
+}
+
+// CheckResult is the status of one dependency.
+type CheckResult struct {
+	Status    string `json:"status"` // up | down | disabled
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// buildInfo is included in every health response.
+func (h *HealthHandler) buildInfo() gin.H {
+	return gin.H{
+		"version":    global.AppVersion,
+		"commit":     global.GitCommit,
+		"build_time": global.BuildTime,
+		"go":         runtime.Version(),
+		"uptime":     time.Since(h.started).Round(time.Second).String(),
+	}
+}
+
+// MarkShuttingDown makes /readyz fail while in-flight requests drain.
+func (h *HealthHandler) MarkShuttingDown() { h.draining.Store(true) }
+
+// Liveness handles GET /healthz: the process is up and serving; no dependency checks,
+// so a database outage does not get the pod restarted.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "build": h.buildInfo()})
+}
+
+// Readiness handles GET /readyz: pings the DB pool and Redis; 503 if any hard dependency is down.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	checks := map[string]CheckResult{
+		"database": h.check(c.Request.Context(), h.pingDB),
+		"redis":    h.check(c.Request.Context(), h.pingRedis),
+	}
+	status, code := "ok", http.StatusOK
+	for _, r := range checks {
+		if r.Status == "down" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	if h.draining.Load() {
+		status, code = "shutting_down", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks, "build": h.buildInfo()})
+}
+
+// check runs ping with a timeout and measures it; a nil ping means the dependency is disabled.
+func (h *HealthHandler) check(ctx context.Context, ping func(context.Context) error) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := ping(ctx)
+	res := CheckResult{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+	switch {
+	case errors.Is(err, errDisabled):
+		res = CheckResult{Status: "disabled"}
+	case err != nil:
+		res.Status, res.Error = "down", err.Error()
+	}
+	return res
+}
+
+// errDisabled marks an optional dependency that is not configured.
+var errDisabled = errors.New("disabled")
+
+func (h *HealthHandler) pingDB(ctx context.Context) error {
+	sqlDB, err := h.db.DB() // Underlying *sql.DB pool.
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *HealthHandler) pingRedis(ctx context.Context) error {
+	if h.rdb == nil {
+		return errDisabled
+	}
+	return h.rdb.Ping(ctx).Err()
+}
@@ -0,0 +1,19 @@
+package handlers // Controller layer translates HTTP <-> service calls.
+
+import ( // Imports for the JWKS endpoint.
+	"net/http" // Status codes.
+
+	"HelmyTask/utils/jwtauth" // Key set that knows our public keys.
+
+	"github.com/gin-gonic/gin" // Gin web framework.
+)
+
+// JWKS handles GET /.well-known/jwks.json: public keys (by kid) for verifying our JWTs.
+// Empty when signing with HS256, since shared secrets are never published.
+func JWKS(keys *jwtauth.KeySet) gin.HandlerFunc {
+	doc := keys.JWKS() // Keys are fixed at startup; build once.
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300") // Let verifiers cache, but pick up rotations quickly.
+		c.JSON(http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers // Controller layer translates HTTP <-> service calls.
+
+import ( // Imports for the log viewer endpoints.
+	"context"  // Tail lifetime.
+	"errors"   // Disabled sentinel.
+	"io"       // Stream step signature.
+	"net/http" // Status codes.
+	"strings"  // Level list parsing.
+	"sync"     // One-shot shutdown.
+	"time"     // Time range + heartbeat.
+
+	"HelmyTask/utils/redislog" // Entry storage and filters.
+
+	"github.com/gin-gonic/gin" // Gin web framework.
+)
+
+// logTailHeartbeat keeps idle SSE connections (and proxies in between) from timing out.
+const logTailHeartbeat = 15 * time.Second
+
+// Page size bounds for GET /admin/logs.
+const (
+	defaultLogLimit = 50
+	maxLogLimit     = 500
+)
+
+// LogHandler serves the entries written by redislog (logs:app) to admins.
+type LogHandler struct {
+	logs     *redislog.Logger // Reads the same key it writes.
+	stop     chan struct{}    // Closed by Shutdown; ends every open tail.
+	stopOnce sync.Once
+}
+
+// NewLogHandler wires the app logger whose entries are served.
+func NewLogHandler(logs *redislog.Logger) *LogHandler {
+	return &LogHandler{logs: logs, stop: make(chan struct{})}
+}
+
+// Shutdown ends all open tail streams. http.Server.Shutdown waits for active connections to go
+// idle, which a stream never does, so register this with srv.RegisterOnShutdown.
+func (h *LogHandler) Shutdown() {
+	h.stopOnce.Do(func() { close(h.stop) })
+}
+
+// logQuery is the query string shared by ListLogs and TailLogs; meta[<key>]=<value> is read separately.
+type logQuery struct {
+	Level  string    `form:"level"`                                        // comma-separated, e.g. "warn,error"
+	Q      string    `form:"q"`                                            // message contains (case-insensitive)
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // time >= (RFC 3339)
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // time <= (RFC 3339)
+	Offset int64     `form:"offset" binding:"min=0"`                       // next_offset of the previous page
+	Limit  int       `form:"limit" binding:"min=0"`
+}
+
+// filter binds the query string; it answers 400 and returns false on bad input.
+func (h *LogHandler) filter(c *gin.Context, q *logQuery) (redislog.Filter, bool) {
+	if err := c.ShouldBindQuery(q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return redislog.Filter{}, false
+	}
+	f := redislog.Filter{Contains: q.Q, Since: q.From, Until: q.To, Meta: c.QueryMap("meta")}
+	for _, lv := range strings.Split(q.Level, ",") {
+		switch lv = strings.TrimSpace(lv); lv {
+		case "":
+		case "info", "warn", "error":
+			f.Levels = append(f.Levels, lv)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "level must be info, warn or error"})
+			return redislog.Filter{}, false
+		}
+	}
+	return f, true
+}
+
+// ListLogs handles GET /admin/logs: matching entries newest first, paged with offset/next_offset.
+func (h *LogHandler) ListLogs(c *gin.Context) {
+	var q logQuery
+	f, ok := h.filter(c, &q)
+	if !ok {
+		return
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultLogLimit
+	}
+	if q.Limit > maxLogLimit {
+		q.Limit = maxLogLimit
+	}
+	page, err := h.logs.Query(c.Request.Context(), f, q.Offset, q.Limit)
+	if contextDone(c, err) {
+		return
+	}
+	if errors.Is(err, redislog.ErrDisabled) { // No Redis → nothing was ever stored.
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "log storage disabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// TailLogs handles GET /admin/logs/tail: a Server-Sent-Events stream of new matching entries
+// ("event: log", JSON data) until the client disconnects or the server shuts down.
+// Paging parameters are ignored.
+func (h *LogHandler) TailLogs(c *gin.Context) {
+	var q logQuery
+	f, ok := h.filter(c, &q)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(c.Request.Context()) // Cancelled on return → Tail unsubscribes.
+	defer cancel()
+	entries, err := h.logs.Tail(ctx, f)
+	if contextDone(c, err) {
+		return
+	}
+	if errors.Is(err, redislog.ErrDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "log storage disabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The server's write timeout would cut the stream; clients reconnect if this is unsupported.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Tell nginx not to buffer the stream.
+	c.Status(http.StatusOK)
+	c.Writer.Flush() // Send headers now so the client knows it is connected.
+
+	heartbeat := time.NewTicker(logTailHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-entries:
+			if !ok { // Client gone (context cancelled) or subscription closed.
+				return false
+			}
+			c.SSEvent("log", e)
+		case <-h.stop: // Server draining; clients reconnect to another instance.
+			return false
+		case <-heartbeat.C:
+			_, _ = io.WriteString(w, ": ping\n\n") // SSE comment; ignored by clients.
+		}
+		return true
+	})
+}
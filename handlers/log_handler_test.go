@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"HelmyTask/handlers"
+	"HelmyTask/utils/redislog"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestListLogs_FiltersAndPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	rlog := redislog.New(rdb, "logs:app", 100, time.Hour, redislog.WithBufferSize(0))
+	rlog.Info("login success", map[string]string{"user_id": "1"})
+	rlog.Warn("login wrong password", map[string]string{"user_id": "1"})
+	rlog.Warn("login wrong password", map[string]string{"user_id": "2"})
+	rlog.Error("LOGIN db error", map[string]string{"user_id": "1"})
+
+	r := gin.New()
+	r.GET("/admin/logs", handlers.NewLogHandler(rlog).ListLogs)
+	get := func(query string) (int, redislog.Page) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/logs?"+query, nil))
+		var p redislog.Page
+		_ = json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+
+	code, p := get("level=warn,error&meta[user_id]=1")
+	if code != http.StatusOK || len(p.Items) != 2 || p.Items[0].Msg != "LOGIN db error" {
+		t.Fatalf("want warn+error of user 1 newest first, got %d %+v", code, p)
+	}
+
+	_, p = get("q=login&limit=3")
+	if len(p.Items) != 3 || p.NextOffset != 3 {
+		t.Fatalf("want a full first page with next_offset 3, got %+v", p)
+	}
+	_, p = get("q=login&limit=3&offset=3")
+	if len(p.Items) != 1 || p.Items[0].Msg != "login success" || p.NextOffset != 0 {
+		t.Fatalf("want the last entry and no next page, got %+v", p)
+	}
+
+	if code, _ := get("level=debug"); code != http.StatusBadRequest {
+		t.Fatalf("want 400 for unknown level, got %d", code)
+	}
+}
+
+func TestTailLogs_EndsOnShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	rlog := redislog.New(rdb, "logs:app", 100, time.Hour, redislog.WithBufferSize(0))
+	h := handlers.NewLogHandler(rlog)
+
+	r := gin.New()
+	r.GET("/admin/logs/tail", h.TailLogs)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/admin/logs/tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want 200, got %d", resp.StatusCode)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body) // Returns once the handler ends the stream.
+		done <- err
+	}()
+	h.Shutdown()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("want a clean end of stream, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tail still open after Shutdown")
+	}
+}
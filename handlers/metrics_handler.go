@@ -0,0 +1,25 @@
+package handlers // Controller layer translates HTTP <-> service calls.
+
+import ( // Imports for the metrics endpoint.
+	"crypto/subtle" // Constant-time token check.
+	"net/http"      // Status codes.
+
+	"HelmyTask/utils/metrics" // Prometheus registry.
+
+	"github.com/gin-gonic/gin" // Gin web framework.
+)
+
+// Metrics handles GET /metrics (metrics_path): the Prometheus exposition.
+// A non-empty token must be sent as "Authorization: Bearer <token>"; empty leaves it open (private port/network).
+func Metrics(token string) gin.HandlerFunc {
+	h := metrics.Handler()
+	want := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), want) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
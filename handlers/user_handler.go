@@ -1,12 +1,16 @@
 package handlers // Controller layer translates HTTP <-> service calls.
 
 import ( // Imports needed by handlers.
+	"errors" // Matching service sentinel errors.
 	"net/http" // Status codes and HTTP primitives.
 	"strconv" // String->int parsing for URL params.
 	"time" // For passing JWT expiration to service login.
 
+	"HelmyTask/global" // Context keys set by middlewares.Auth.
 	"HelmyTask/models" // Request/response DTOs.
+	"HelmyTask/repositories" // IsNotFound helper.
 	"HelmyTask/services" // Use-case interface.
+	"HelmyTask/utils/jwtauth" // JWT signer type.
 
 	"github.com/gin-gonic/gin" // Gin web framework.
 )
@@ -14,13 +18,13 @@ import ( // Imports needed by handlers.
 // UserHandler bundles dependencies needed by user endpoints.
 type UserHandler struct {
 	svc        services.UserService // Injected business logic.
-	jwtSecret  string // JWT signing secret configured in main.
+	signer     jwtauth.Signer // JWT signing keys configured in main.
 	jwtExpires time.Duration // JWT validity duration.
 }
 
 // NewUserHandler constructs a handler for users with its dependencies.
-func NewUserHandler(svc services.UserService, jwtSecret string, jwtExp time.Duration) *UserHandler {
-	return &UserHandler{svc: svc, jwtSecret: jwtSecret, jwtExpires: jwtExp} // Return pointer for methods.
+func NewUserHandler(svc services.UserService, signer jwtauth.Signer, jwtExp time.Duration) *UserHandler {
+	return &UserHandler{svc: svc, signer: signer, jwtExpires: jwtExp} // Return pointer for methods.
 }
 
 // Register handles POST /auth/register (public).
@@ -30,7 +34,10 @@ func (h *UserHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // 400 if validation fails.
 		return // Stop handler here.
 	}
-	u, err := h.svc.Register(req) // Delegate to service (hash + save + optional cache warm).
+	u, err := h.svc.Register(c.Request.Context(), req) // Delegate to service (hash + save + optional cache warm).
+	if contextDone(c, err) {
+		return
+	}
 	if err != nil { // Typically "email already exists".
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Report error to client.
 		return
@@ -45,12 +52,304 @@ func (h *UserHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // 400 on invalid input.
 		return
 	}
-	tok, err := h.svc.Login(req, h.jwtSecret, h.jwtExpires) // Delegate to service (validates + signs JWT).
+	req.ClientIP = c.ClientIP() // Per-IP brute-force counter.
+	resp, err := h.svc.Login(c.Request.Context(), req, h.signer, h.jwtExpires) // Delegate to service (validates + signs JWT).
+	if contextDone(c, err) {
+		return
+	}
+	var locked *services.LockedError
+	if errors.As(err, &locked) { // Too many failures → 429 with a hint when to retry.
+		c.Header("Retry-After", strconv.Itoa(locked.RetryAfterSeconds()))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": locked.RetryAfterSeconds()})
+		return
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) { // Right password, unconfirmed email → 403.
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil { // Wrong credentials → 401 Unauthorized.
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, models.AuthResponse{Token: tok}) // Return {"token": "..."}.
+	c.JSON(http.StatusOK, resp) // Return {"token": "...", "refresh_token": "...", ...}.
+}
+
+// Refresh handles POST /auth/refresh (public; the refresh token is the credential).
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest // Allocate request payload struct.
+	if err := c.ShouldBindJSON(&req); err != nil { // Bind/validate JSON.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken, h.signer, h.jwtExpires) // Rotate token.
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil { // Invalid, expired or reused → 401.
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp) // New pair; the old refresh token is now spent.
+}
+
+// ForgotPassword handles POST /auth/password/forgot (public).
+// Always answers 202 so callers cannot probe which emails exist.
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.ForgotPassword(c.Request.Context(), req.Email); err != nil { // Infra failure only.
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send reset email"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email exists, a reset token was sent"})
+}
+
+// ResetPassword handles POST /auth/password/reset (public; the token is the credential).
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail handles GET /auth/verify-email?token=... and POST /auth/verify-email {"token": "..."}.
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil { // Query for GET, JSON body for POST.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u, err := h.svc.VerifyEmail(c.Request.Context(), req.Token)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// ResendVerification handles POST /auth/verify-email/resend (public, throttled per user; always 202).
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.svc.ResendVerification(c.Request.Context(), req.Email)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not send verification email"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account needs verification, an email was sent"})
+}
+
+// Logout handles POST /auth/logout (protected): revokes the current JWT and optional refresh token.
+func (h *UserHandler) Logout(c *gin.Context) {
+	var req models.LogoutRequest // Body is optional.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	uid := c.GetUint(global.CtxUserIDKey) // Set by middlewares.Auth.
+	jti := c.GetString(global.CtxTokenIDKey) // ID of the token being logged out.
+	exp := c.GetTime(global.CtxTokenExpKey) // Deny-list entry lives until this.
+	if err := h.svc.Logout(c.Request.Context(), uid, jti, exp, req.RefreshToken); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll handles POST /auth/logout-all (protected): revokes every session of the caller.
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	if err := h.svc.LogoutAll(c.Request.Context(), c.GetUint(global.CtxUserIDKey)); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetMe handles GET /me: the user behind the bearer token.
+func (h *UserHandler) GetMe(c *gin.Context) {
+	u, err := h.svc.GetUser(c.Request.Context(), c.GetUint(global.CtxUserIDKey)) // uid set by middlewares.Auth.
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil { // Token outlived the account → 404.
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// UpdateMe handles PATCH /me (name/email only; password has its own endpoint).
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role != nil || req.Password != nil { // Not self-service through PATCH.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role and password cannot be changed here"})
+		return
+	}
+	u, err := h.svc.UpdateUser(c.Request.Context(), c.GetUint(global.CtxUserIDKey), req)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// DeleteMe handles DELETE /me (closes the caller's own account and its sessions).
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	uid := c.GetUint(global.CtxUserIDKey)
+	if err := h.svc.DeleteUser(c.Request.Context(), uid); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.Status(http.StatusNoContent) // DeleteUser already revoked every session.
+}
+
+// ChangePassword handles POST /me/password.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.ChangePassword(c.Request.Context(), c.GetUint(global.CtxUserIDKey), req.CurrentPassword, req.NewPassword); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent) // All sessions revoked; client logs in again.
+}
+
+// VerifyMFA handles POST /auth/mfa/verify: second login step with a TOTP or recovery code.
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.svc.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, h.signer, h.jwtExpires)
+	if contextDone(c, err) {
+		return
+	}
+	var locked *services.LockedError
+	if errors.As(err, &locked) { // Too many wrong codes → same lock as Login.
+		c.Header("Retry-After", strconv.Itoa(locked.RetryAfterSeconds()))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": locked.RetryAfterSeconds()})
+		return
+	}
+	if err != nil { // Bad code or challenge → 401 (client restarts login).
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnrollTOTP handles POST /me/mfa/totp/enroll.
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	enr, err := h.svc.EnrollTOTP(c.Request.Context(), c.GetUint(global.CtxUserIDKey))
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, enr) // Secret + otpauth:// URI for the QR code.
+}
+
+// ConfirmTOTP handles POST /me/mfa/totp/confirm.
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.ConfirmTOTP(c.Request.Context(), c.GetUint(global.CtxUserIDKey), req.Code)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP handles POST /me/mfa/totp/disable.
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	var req models.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.DisableTOTP(c.Request.Context(), c.GetUint(global.CtxUserIDKey), req.Password, req.Code); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /me/mfa/recovery-codes.
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), c.GetUint(global.CtxUserIDKey), req.Code)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
 }
 
 // GetUser handles GET /users/:id (protected).
@@ -60,7 +359,10 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	u, err := h.svc.GetUser(id) // Fetch user (cache-aware).
+	u, err := h.svc.GetUser(c.Request.Context(), id) // Fetch user (cache-aware).
+	if contextDone(c, err) {
+		return
+	}
 	if err != nil { // Not found → 404.
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
@@ -75,7 +377,10 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, err := h.svc.CreateUser(req) // Service creates user (hash + uniqueness).
+	u, err := h.svc.CreateUser(c.Request.Context(), req) // Service creates user (hash + uniqueness).
+	if contextDone(c, err) {
+		return
+	}
 	if err != nil { // Business error → 400.
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -95,7 +400,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, err := h.svc.UpdateUser(id, req) // Update via service (hash if password; refresh cache).
+	if req.Role != nil && c.GetString(global.CtxRoleKey) != models.RoleAdmin { // Only admins change roles.
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can change roles"})
+		return
+	}
+	if req.Password != nil && c.GetString(global.CtxRoleKey) != models.RoleAdmin { // Self-service goes through /me/password (checks the current one).
+		c.JSON(http.StatusForbidden, gin.H{"error": "use POST /me/password to change your password"})
+		return
+	}
+	u, err := h.svc.UpdateUser(c.Request.Context(), id, req) // Update via service (hash if password; refresh cache).
+	if contextDone(c, err) {
+		return
+	}
 	if err != nil { // Could be "email exists" or not found.
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -110,20 +426,91 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.svc.DeleteUser(id); err != nil { // Service delete (also clears cache).
+	if err := h.svc.DeleteUser(c.Request.Context(), id); err != nil { // Service delete (also clears cache).
+		if contextDone(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"}) // Simplified mapping to 404.
 		return
 	}
 	c.Status(http.StatusNoContent) // 204 No Content on success (typical REST delete).
 }
 
-// ListUsers handles GET /users?page=1&limit=10 (protected).
+// RestoreUser handles POST /users/:id/restore (admin): undoes a soft delete.
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id, err := parseUint(c.Param("id")) // Parse :id.
+	if err != nil { // Invalid ID → 400.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	u, err := h.svc.RestoreUser(c.Request.Context(), id)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		if repositories.IsNotFound(err) { // Unknown, never deleted, or already purged.
+			c.JSON(http.StatusNotFound, gin.H{"error": "deleted user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// ListDeletedUsers handles GET /users/deleted?page=1&limit=10 (admin).
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	paged, err := h.svc.ListDeletedUsers(c.Request.Context(), page, limit)
+	if contextDone(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, paged)
+}
+
+// UnlockUser handles POST /users/:id/unlock (admin): lifts a login lockout.
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := parseUint(c.Param("id")) // Parse :id.
+	if err != nil { // Invalid ID → 400.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.UnlockUser(c.Request.Context(), id); err != nil {
+		if contextDone(c, err) {
+			return
+		}
+		if repositories.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListUsers handles GET /users?page=1&limit=10&q=&role=&status=&created_from=&created_to=&sort= (admin).
+// paging=cursor (or a cursor=...) switches to keyset pages with next_cursor/prev_cursor.
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	// Parse query params with defaults; we let the service clamp them too.
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1")) // Default page=1.
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10")) // Default limit=10.
+	var q models.ListUserQuery // Filters + paging; the service clamps page/limit.
+	if err := c.ShouldBindQuery(&q); err != nil { // Bad role/status/date → 400.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	paged, err := h.svc.ListUsers(page, limit) // Get page via service (items + total + page + limit).
+	paged, err := h.svc.ListUsers(c.Request.Context(), q) // Get page via service (items + total + page + limit).
+	if contextDone(c, err) {
+		return
+	}
+	if errors.Is(err, repositories.ErrInvalidSort) || errors.Is(err, services.ErrInvalidCursor) { // Bad sort field or cursor → 400.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil { // Internal error → 500.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"HelmyTask/global"
+	"HelmyTask/handlers"
+	"HelmyTask/models"
+	"HelmyTask/repositories"
+	"HelmyTask/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newUserDB opens a private in-memory DB with the users table.
+func newUserDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1) // Each connection to :memory: is its own database.
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestUpdateMe_RejectsRoleAndPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := services.NewUserService(repositories.NewUserRepository(newUserDB(t)), nil, nil)
+	u, err := svc.CreateUser(context.Background(), models.RegisterRequest{Name: "lina", Email: "lina@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(global.CtxUserIDKey, u.ID) }) // Stands in for middlewares.Auth.
+	r.PATCH("/me", handlers.NewUserHandler(svc, nil, time.Minute).UpdateMe)
+	patch := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/me", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := patch(`{"role":"admin"}`); code != http.StatusBadRequest {
+		t.Fatalf("want 400 for a self-promotion, got %d", code)
+	}
+	if code := patch(`{"name":"x","password":"newpass567"}`); code != http.StatusBadRequest {
+		t.Fatalf("want 400 for a password change via PATCH /me, got %d", code)
+	}
+	got, err := svc.GetUser(context.Background(), u.ID)
+	if err != nil || got.Role != models.RoleUser || got.Name != "lina" {
+		t.Fatalf("rejected requests must not change the user, got %+v (%v)", got, err)
+	}
+
+	if code := patch(`{"name":"lina b"}`); code != http.StatusOK {
+		t.Fatalf("want 200 for a name change, got %d", code)
+	}
+}
+
+func TestLogin_LockedReturns429WithRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	svc := services.NewUserService(repositories.NewUserRepository(newUserDB(t)), rdb, nil,
+		services.WithLockout(services.LockoutConfig{MaxAccountFailures: 2, Window: time.Minute, BaseLockout: 90 * time.Second, MaxLockout: time.Hour}))
+	if _, err := svc.CreateUser(context.Background(), models.RegisterRequest{Name: "nour", Email: "nour@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/auth/login", handlers.NewUserHandler(svc, nil, time.Minute).Login)
+	login := func(password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"nour@example.com","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := login("wrong-pass"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("first failure: want 401, got %d", w.Code)
+	}
+	login("wrong-pass") // Second failure starts the 90s lock.
+	w := login("secret123")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("while locked: want 429 even with the right password, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "90" {
+		t.Fatalf("want Retry-After 90, got %q", got)
+	}
+}
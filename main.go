@@ -1,31 +1,46 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"HelmyTask/config"
+	"HelmyTask/handlers"
+	"HelmyTask/middlewares"
 	"HelmyTask/repositories"
 	"HelmyTask/routes"
 	"HelmyTask/services"
-	"HelmyTask/utils/redislog"
+	"HelmyTask/utils/mailer"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// SIGINT/SIGTERM cancel ctx; everything long-running watches it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 1) Load config from file and||or env
 	cfg := config.Load() // Returns *config.Config with merged settings.
+	config.InitLogger(cfg) // slog default; log.Printf output goes through it too.
 	log.Printf("[boot] %s starting in %s on :%s", cfg.AppName, cfg.Env, cfg.HTTPPort)
 
+	shutdownTracing := config.InitTracing(ctx, cfg) // Global tracer provider + W3C propagation.
+
 	// 2) Initialize infrastructure (DB and Redis).
 	db := config.InitDB(cfg)     // Open DB based on cfg.DBDriver and run migrations.
 	// _ = config.InitRedis(cfg)    // Create Redis client (available for future use).==================================================================
 	rdb := config.InitRedis(cfg) // single Redis client (Ping verified)
 
 	
-	// 3) Build Redis logger (list key: logs:app)
-	rlog := redislog.New(rdb, "logs:app", 1000, 7*24*time.Hour)
+	// 3) Build app logger (sinks from redislog_sinks, default list logs:app; batched in the background)
+	rlog := config.InitRedisLog(cfg, rdb)
 	rlog.Info("app boot", map[string]string{
 		"env":   cfg.Env,
 		"port":  cfg.HTTPPort,
@@ -34,7 +49,47 @@ func main() {
 
 	// 4) Construct repositories and services (dependency injection).
 	userRepo := repositories.NewUserRepository(db) // Repo uses *gorm.DB to talk to chosen DB.
-	userSvc := services.NewUserService(userRepo, rdb, rlog)  // Service wraps business rules and JWT issuance.
+	svcOpts := []services.Option{
+		services.WithRefreshTTL(config.RefreshExpiryDuration), // Refresh token lifetime from config.
+		services.WithPasswordResetTTL(config.ResetExpiryDuration), // Reset token lifetime from config.
+		services.WithMailer(mailer.New(cfg.Mailer, cfg.MailerFile, cfg.Env == "dev")), // log|file mailer; log prints bodies (tokens) only in dev.
+		services.WithRequireVerifiedEmail(cfg.RequireVerifiedEmail), // Block unverified logins if enabled.
+		services.WithEmailVerifyTTL(config.VerifyExpiryDuration), // Verification token lifetime.
+		services.WithVerifyResendInterval(config.VerifyResendDuration), // Resend throttle.
+		services.WithMFAIssuer(cfg.AppName), // Label shown in authenticator apps.
+		services.WithCursorSecret(config.CursorKey(cfg)), // Signs list cursors (own key, not jwt_secret).
+		services.WithLockout(services.LockoutConfig{ // Brute-force thresholds (Redis only).
+			MaxAccountFailures: cfg.LockoutMaxAccountFailures,
+			MaxIPFailures:      cfg.LockoutMaxIPFailures,
+			Window:             config.MustDuration("lockout_window", cfg.LockoutWindow),
+			BaseLockout:        config.MustDuration("lockout_base", cfg.LockoutBase),
+			MaxLockout:         config.MustDuration("lockout_max", cfg.LockoutMax),
+		}),
+	}
+	if rdb == nil { // Redis disabled → keep refresh/reset tokens in the DB instead.
+		svcOpts = append(svcOpts,
+			services.WithRefreshTokenRepository(repositories.NewRefreshTokenRepository(db)),
+			services.WithOneTimeTokenRepository(repositories.NewOneTimeTokenRepository(db)),
+		)
+	}
+	userSvc := services.NewUserService(userRepo, rdb, rlog, svcOpts...)  // Service wraps business rules and JWT issuance.
+	if config.TracingEnabled(cfg) {
+		userSvc = services.NewTracedUserService(userSvc) // A span per service call.
+	}
+	if cfg.AdminEmail != "" { // Make sure the first admin exists.
+		if err := userSvc.BootstrapAdmin(ctx, cfg.AdminName, cfg.AdminEmail, cfg.AdminPassword); err != nil {
+			log.Fatalf("[boot] admin bootstrap failed: %v", err)
+		}
+	}
+
+	// Permanently remove users soft-deleted longer than the retention period.
+	purgeDone := make(chan struct{}) // Closed when the purge loop has returned (shutdown waits for it).
+	go func() {
+		defer close(purgeDone)
+		services.RunUserPurge(ctx, userSvc,
+			config.MustDuration("user_purge_retention", cfg.UserPurgeRetention),
+			config.MustDuration("user_purge_interval", cfg.UserPurgeInterval))
+	}()
 
 	// 5) Create Gin engine and wire routes
 	r := gin.New()                                  // Create a new bare Gin engine (no default middleware).
@@ -44,16 +99,103 @@ func main() {
 _ = r.SetTrustedProxies(nil)
 // or trust only local proxies
 // _ = r.SetTrustedProxies([]string{"127.0.0.1"})
-	jwtExp, _ := time.ParseDuration(cfg.JWTExpires) // Convert "72h" to time.Duration (ignore parse err due to defaults).
-	routes.Setup(r, userSvc, cfg.JWTSecret, jwtExp) // Attach middlewares and endpoints.
+	jwtExp, _ := time.ParseDuration(cfg.JWTExpires) // Convert "15m" to time.Duration (ignore parse err due to defaults).
+	jwtKeys := config.InitJWTKeys(cfg) // HS256 secret or PEM keys (RS256/ES256/EdDSA) with kid.
+	tokens := config.InitTokenValidator(cfg, jwtKeys) // iss/aud/leeway/alg policy for signing + verifying.
+	limits := config.InitRateLimits(cfg, rdb) // Redis sliding window (in-memory when Redis is off).
+	health := handlers.NewHealthHandler(db, rdb) // /healthz + /readyz.
+	reqTimeout := config.MustDuration("request_timeout", cfg.RequestTimeout) // Deadline on each request's context.
+	logViewer := handlers.NewLogHandler(rlog) // /admin/logs reads what rlog writes.
+	routes.Setup(r, userSvc, tokens, jwtExp, reqTimeout, limits, health, logViewer) // Attach middlewares and endpoints.
 
+	// Prometheus scrape endpoint: on the API port, or on its own listener when metrics_addr is set.
+	var metricsSrv *http.Server
+	if cfg.MetricsEnabled {
+		if cfg.MetricsAddr == "" {
+			r.GET(cfg.MetricsPath, handlers.Metrics(cfg.MetricsToken))
+		} else {
+			mr := gin.New()
+			mr.Use(middlewares.Recovery())
+			mr.GET(cfg.MetricsPath, handlers.Metrics(cfg.MetricsToken))
+			metricsSrv = config.InitMetricsServer(cfg, mr)
+		}
+	}
+
+	// 6) Start HTTP server (with timeouts) in the background and wait for a signal or a failure.
+	srv := config.InitHTTPServer(cfg, r)
+	srv.RegisterOnShutdown(logViewer.Shutdown) // Shutdown waits for open SSE tails otherwise.
+	serveErr := make(chan error, 2) // One slot per server so neither goroutine blocks.
+	go func() {
+		rlog.Info("http server start", map[string]string{"port": cfg.HTTPPort})
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err // e.g. port already in use
+		}
+	}()
+	if metricsSrv != nil {
+		go func() {
+			rlog.Info("metrics server start", map[string]string{"addr": cfg.MetricsAddr})
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+	}
 
-	rlog.Info("http server start", map[string]string{"port": cfg.HTTPPort})
-	if err := r.Run(":" + cfg.HTTPPort); err != nil {
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Printf("[shutdown] signal received, draining (max %s)", cfg.ShutdownTimeout)
+	case err := <-serveErr:
 		rlog.Error("http server error", map[string]string{"err": err.Error()})
+		log.Printf("[shutdown] http server error: %v", err)
+		exitCode = 1
+	}
+	stop() // A second signal now kills the process immediately.
+	health.MarkShuttingDown() // /readyz → 503 so no new traffic is routed here.
+	if d := config.MustDuration("shutdown_drain_delay", cfg.ShutdownDrainDelay); d > 0 && exitCode == 0 {
+		// Keep serving until the load balancer has seen the failing probe; Shutdown closes the listener.
+		log.Printf("[shutdown] readiness off, waiting %s before closing the listener", d)
+		time.Sleep(d)
+	}
+
+	// 7) Graceful shutdown: stop accepting, let in-flight requests finish, then release resources.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.MustDuration("shutdown_timeout", cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil { // Deadline hit → remaining connections are cut.
+		log.Printf("[shutdown] http: %v", err)
+		exitCode = 1
+	}
+	if metricsSrv != nil { // Scrapes are short; same deadline.
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[shutdown] metrics: %v", err)
+		}
+	}
+	select { // ctx is cancelled, so a running purge aborts; it must not touch DB/Redis after they close.
+	case <-purgeDone:
+	case <-shutdownCtx.Done():
+		log.Printf("[shutdown] user purge still running at the deadline")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil { // Export buffered spans.
+		log.Printf("[shutdown] tracing: %v", err)
+	}
+	rlog.Info("http server stopped", nil)
+	if err := rlog.Close(shutdownCtx); err != nil { // Flush logs while Redis is still open.
+		log.Printf("[shutdown] redislog: %v", err)
+	}
+	if st := rlog.Stats(); st.Dropped+st.Failed > 0 {
+		log.Printf("[shutdown] redislog lost entries: dropped=%d failed=%d", st.Dropped, st.Failed)
+	}
+	if rdb != nil {
+		if err := rdb.Close(); err != nil {
+			log.Printf("[shutdown] redis: %v", err)
+		}
+	}
+	if sqlDB, err := db.DB(); err == nil { // Close the GORM connection pool.
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("[shutdown] db: %v", err)
+		}
 	}
-	// 6) Start HTTP server on configured port; fatal if it fails to bind.
-	if err := r.Run(":" + cfg.HTTPPort); err != nil {
-		log.Fatal(err) // Stop the process if server fails to start.
+	log.Printf("[shutdown] done")
+	if exitCode != 0 {
+		os.Exit(exitCode) // Deferred stop/cancel are no longer needed at this point.
 	}
 }
@@ -4,19 +4,36 @@
 package middlewares
 
 import (
+	"context" // Request context for the revocation lookup.
+	"errors"
 	"net/http"
 	"strconv" // Convert string claim to int when needed.
+	"time"    // Token expiry exposed to handlers.
 
 	"HelmyTask/global" // For the context key to store user ID.
+	"HelmyTask/utils/jwtauth" // Validation error codes.
 
 	"github.com/gin-gonic/gin"     // Gin context/request/response types
 	"github.com/golang-jwt/jwt/v5" // JWT parsing and validation
 )
 
+// RevocationChecker reports whether a token was revoked server-side (logout).
+// services.UserService satisfies it; pass nil to skip the check.
+type RevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, jti string, userID uint, version int64) (bool, error)
+}
+
+// TokenParser verifies a raw JWT and returns its claims. *jwtauth.Validator implements it,
+// checking alg, signature (by kid), exp/nbf/iat, iss and aud.
+type TokenParser interface {
+	Parse(raw string) (jwt.MapClaims, error)
+}
+
 // Auth returns a Gin middleware that validates "Authorization: Bearer <token>"
 // and injects the user ID ("uid") into the request context if the token is valid.
-func Auth(jwtSecret string) gin.HandlerFunc {
-	return func(c *gin.Context) { // Middleware function closure captures jwtSecret. 
+// Tokens whose jti or version were revoked are rejected as well.
+func Auth(parser TokenParser, revoked RevocationChecker) gin.HandlerFunc {
+	return func(c *gin.Context) { // Middleware function closure captures the parser. 
 		auth := c.GetHeader("Authorization") //read authorization header from request
 		// Quick check : must start with "bearer" and be long 
 		if len(auth) < 8 || auth[:7] != "Bearer " {
@@ -25,31 +42,57 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		}
 		raw := auth[7:] //extract the token substring after "Bearer"
 
-		// parse and validate token signature using the shared secret
-		t, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-		//reject with 401 if the token is not valid or if an error exist 
-		if err != nil || !t.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			return
-		}
-		//we expect MapClaims (string any map) to exract tored fields 
-		claims, ok := t.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid claims"})
+		// parse and validate token signature with the key named by its kid
+		claims, err := parser.Parse(raw)
+		//reject with 401 if the token is not valid; the code tells expired (refresh) from the rest (re-login)
+		if err != nil {
+			code := jwtauth.CodeInvalid
+			var ve *jwtauth.ValidationError
+			if errors.As(err, &ve) {
+				code = ve.Code
+			}
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token", error_description="`+code+`"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token", "code": code})
 			return
 		}
 		// extract subject (user ID) from the claims and normalize its type 
+		var uid uint
 		sub := claims["sub"]
 		switch v := sub.(type) {
 		case float64: // JSON numbers often decode to float64; cast to uint.
-			c.Set(global.CtxUserIDKey, uint(v))
+			uid = uint(v)
 		case string: // Sometimes IDs may be strings; try to parse.
 			if n, err := strconv.Atoi(v); err == nil {
-				c.Set(global.CtxUserIDKey, uint(n))
+				uid = uint(n)
 			}
 		}
+		jti, _ := claims["jti"].(string) // Missing on tokens minted before revocation existed.
+		ver, _ := claims["ver"].(float64) // Token version; 0 when absent.
+
+		// server-side revocation (logout / logout everywhere)
+		if revoked != nil {
+			isRevoked, err := revoked.IsTokenRevoked(c.Request.Context(), jti, uid, int64(ver))
+			if err != nil { // Can't tell → fail closed.
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token check unavailable"})
+				return
+			}
+			if isRevoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+				return
+			}
+		}
+
+		if uid != 0 {
+			c.Set(global.CtxUserIDKey, uid)
+		}
+		role, _ := claims["role"].(string) // Empty for tokens minted before roles existed.
+		c.Set(global.CtxRoleKey, role)
+		c.Set(global.CtxTokenIDKey, jti)
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			c.Set(global.CtxTokenExpKey, exp.Time)
+		} else {
+			c.Set(global.CtxTokenExpKey, time.Time{})
+		}
 		c.Next() // Continue to the actual handler. 
 	}
 }
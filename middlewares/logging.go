@@ -3,23 +3,34 @@
 package middlewares
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-//RequestLogger prints method , path , status and duration for each request 
-//simple and effective for local dev ;;; in prod you might use structured logging 
+//RequestLogger writes one slog line per request: method, path, status and duration.
+//request_id/trace_id come from the context; 5xx log at error, 4xx at warn.
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now() //erecord start time
 		path := c.Request.URL.Path //// Keep the path for logging (useful after c.Next()).
 		c.Next() // Run downstream handlers/middlewares.
-		log.Printf("%s %s %d %s",  //log  linee
-		c.Request.Method, //http method (get , POST ,etc ...)
-		 path, //request path 
-		  c.Writer.Status(), //final status code
-		  time.Since(start)) //elapsed time
+		status := c.Writer.Status() //final status code
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method), //http method (get , POST ,etc ...)
+			slog.String("path", path), //request path
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)), //elapsed time
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
 }
@@ -0,0 +1,24 @@
+// Prometheus request metrics labelled by route template.
+
+package middlewares
+
+import (
+	"time"
+
+	"HelmyTask/utils/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts requests and observes latency per method, route template and status.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath() // "/api/v1/users/:id", not the raw path → bounded label set.
+		if route == "" {
+			route = "unmatched" // 404s would otherwise explode cardinality.
+		}
+		metrics.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
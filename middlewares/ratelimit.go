@@ -0,0 +1,233 @@
+// request throttling: sliding-window limits in Redis, with an in-process fallback.
+
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"HelmyTask/global" // User ID set by Auth.
+	"HelmyTask/utils"  // Random member IDs for the Redis window.
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// What a rate limit is keyed by.
+const (
+	RateKeyIP     = "ip"      // client IP (works before Auth)
+	RateKeyUser   = "user"    // authenticated user ID, IP when anonymous
+	RateKeyAPIKey = "api_key" // X-API-Key header, IP when absent
+)
+
+// RateLimitRule is one limit: at most Limit requests per Window for each key.
+type RateLimitRule struct {
+	Name   string        // Counter namespace, e.g. "auth" or "api".
+	Limit  int           // 0 disables the rule.
+	Window time.Duration // Sliding window length.
+	KeyBy  string        // RateKeyIP | RateKeyUser | RateKeyAPIKey
+}
+
+// RateLimits is what routes.Setup needs: one limiter and a rule per route group.
+type RateLimits struct {
+	Limiter Limiter       // nil disables throttling
+	Auth    RateLimitRule // public /auth/* endpoints (always per IP)
+	API     RateLimitRule // authenticated endpoints
+}
+
+// RateResult is the outcome of one Allow call.
+type RateResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // until the oldest counted request leaves the window
+}
+
+// Limiter decides whether one more request fits into the window for key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (RateResult, error)
+}
+
+// RateLimit throttles requests according to rule and sets X-RateLimit-* headers.
+// A nil limiter or a zero limit turns it into a no-op; limiter errors let the request through.
+func RateLimit(l Limiter, rule RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil || rule.Limit <= 0 {
+			c.Next()
+			return
+		}
+		key := "ratelimit:" + rule.Name + ":" + rateKey(c, rule.KeyBy)
+		res, err := l.Allow(c.Request.Context(), key, rule.Limit, rule.Window)
+		if err != nil { // Fail open; throttling must not take the API down.
+			slog.WarnContext(c.Request.Context(), "ratelimit unavailable", "rule", rule.Name, "err", err)
+			c.Next()
+			return
+		}
+		reset := int((res.ResetAfter + time.Second - 1) / time.Second) // round up to whole seconds
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(reset))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(reset))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded", "retry_after": reset})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateKey picks the identity a request is counted against.
+func rateKey(c *gin.Context, by string) string {
+	switch by {
+	case RateKeyUser:
+		if uid := c.GetUint(global.CtxUserIDKey); uid != 0 {
+			return "user:" + strconv.FormatUint(uint64(uid), 10)
+		}
+	case RateKeyAPIKey:
+		if k := c.GetHeader("X-API-Key"); k != "" { // Hash so raw keys never land in Redis.
+			sum := sha256.Sum256([]byte(k))
+			return "key:" + hex.EncodeToString(sum[:8])
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ParseRate reads a "<limit>/<window>" setting such as "10/1m". Empty means disabled.
+func ParseRate(s string) (int, time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, 0, nil
+	}
+	n, w, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("rate %q: want <limit>/<window>", s)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("rate %q: bad limit", s)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(w))
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("rate %q: bad window", s)
+	}
+	return limit, window, nil
+}
+
+// ---------------- Redis ----------------
+
+// slidingWindow keeps one sorted-set member per accepted request (score = ms timestamp).
+// KEYS[1]=key ARGV: now(ms), window(ms), limit, member → {allowed, remaining, reset(ms)}
+var slidingWindow = redis.NewScript(`
+local now, window, limit = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then reset = tonumber(oldest[2]) + window - now end
+return {allowed, limit - count, reset}
+`)
+
+type redisLimiter struct {
+	rdb      *redis.Client
+	fallback Limiter // used while Redis is unreachable
+}
+
+// NewRedisLimiter shares counters across instances through Redis.
+// When rdb is nil or a Redis call fails, it counts in process memory instead.
+func NewRedisLimiter(rdb *redis.Client) Limiter {
+	if rdb == nil {
+		return NewMemoryLimiter()
+	}
+	return &redisLimiter{rdb: rdb, fallback: NewMemoryLimiter()}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateResult, error) {
+	member, err := utils.RandomToken(8)
+	if err != nil {
+		return RateResult{}, err
+	}
+	vals, err := slidingWindow.Run(ctx, l.rdb, []string{key},
+		time.Now().UnixMilli(), window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil || len(vals) != 3 {
+		return l.fallback.Allow(ctx, key, limit, window)
+	}
+	return RateResult{
+		Allowed:    vals[0] == 1,
+		Limit:      limit,
+		Remaining:  int(vals[1]),
+		ResetAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// ---------------- In-process ----------------
+
+type memoryLimiter struct {
+	mu   sync.Mutex
+	keys map[string]*memoryWindow
+}
+
+type memoryWindow struct {
+	hits   []time.Time   // accepted request times, oldest first
+	window time.Duration // window of the rule that owns the key
+}
+
+// NewMemoryLimiter counts per process; fine for a single instance or as a Redis fallback.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{keys: map[string]*memoryWindow{}}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (RateResult, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.keys[key]
+	if w == nil {
+		w = &memoryWindow{}
+		l.keys[key] = w
+	}
+	w.window = window
+	w.hits = prune(w.hits, now.Add(-window))
+	res := RateResult{Limit: limit}
+	if len(w.hits) < limit {
+		w.hits = append(w.hits, now)
+		res.Allowed = true
+	}
+	res.Remaining = limit - len(w.hits)
+	res.ResetAfter = window
+	if len(w.hits) > 0 {
+		res.ResetAfter = w.hits[0].Add(window).Sub(now)
+	}
+
+	if len(l.keys) > 10000 { // Drop idle keys now and then so memory stays bounded.
+		for k, v := range l.keys {
+			if len(prune(v.hits, now.Add(-v.window))) == 0 {
+				delete(l.keys, k)
+			}
+		}
+	}
+	return res, nil
+}
+
+// prune drops timestamps at or before cutoff.
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,58 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"HelmyTask/middlewares"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// hit sends n requests through a router guarded by limiter and returns the last response.
+func hit(t *testing.T, limiter middlewares.Limiter, n int) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/x", middlewares.RateLimit(limiter, middlewares.RateLimitRule{Name: "t", Limit: 2, Window: time.Minute, KeyBy: middlewares.RateKeyIP}),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	var w *httptest.ResponseRecorder
+	for i := 0; i < n; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	}
+	return w
+}
+
+func TestRateLimit_RedisAndFallback(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1}) // fail fast once closed
+	limiter := middlewares.NewRedisLimiter(rdb)
+
+	if w := hit(t, limiter, 2); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("second request: code=%d remaining=%q", w.Code, w.Header().Get("X-RateLimit-Remaining"))
+	}
+	w := hit(t, limiter, 1)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("third request: code=%d retry-after=%q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Redis down → in-process counting keeps the limit in force.
+	mr.Close()
+	if w := hit(t, limiter, 3); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("fallback: expected 429, got %d", w.Code)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	if n, w, err := middlewares.ParseRate("10/1m"); err != nil || n != 10 || w != time.Minute {
+		t.Fatalf("got %d %s %v", n, w, err)
+	}
+	if _, _, err := middlewares.ParseRate("10"); err == nil {
+		t.Fatalf("expected error for missing window")
+	}
+}
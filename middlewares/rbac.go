@@ -0,0 +1,51 @@
+// role checks that run after Auth has put uid/role into the context.
+
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"HelmyTask/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets requests through when the token's role is one of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasRole(c, roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrRole allows the request when the :param path value is the caller's own ID,
+// or when the caller has one of roles (e.g. admin managing other users).
+func RequireSelfOrRole(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasRole(c, roles) {
+			c.Next()
+			return
+		}
+		id, err := strconv.ParseUint(c.Param(param), 10, 0)
+		if err != nil || uint(id) != c.GetUint(global.CtxUserIDKey) { // Not yours → 403.
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasRole compares the role set by Auth against the allowed list.
+func hasRole(c *gin.Context, roles []string) bool {
+	role := c.GetString(global.CtxRoleKey)
+	for _, r := range roles {
+		if role != "" && role == r {
+			return true
+		}
+	}
+	return false
+}
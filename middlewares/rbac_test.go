@@ -0,0 +1,64 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HelmyTask/global"
+	"HelmyTask/middlewares"
+	"HelmyTask/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRBAC_AdminSelfAndOtherUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { // Stands in for Auth: uid/role from headers.
+		if c.GetHeader("X-Role") != "" {
+			c.Set(global.CtxRoleKey, c.GetHeader("X-Role"))
+		}
+		c.Set(global.CtxUserIDKey, uint(7))
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	// Same wiring as routes.Setup.
+	admin := middlewares.RequireRole(models.RoleAdmin)
+	selfOrAdmin := middlewares.RequireSelfOrRole("id", models.RoleAdmin)
+	r.GET("/users", admin, ok)
+	r.POST("/users", admin, ok)
+	r.GET("/users/:id", selfOrAdmin, ok)
+	r.PUT("/users/:id", selfOrAdmin, ok)
+	r.DELETE("/users/:id", selfOrAdmin, ok)
+
+	cases := []struct {
+		name, role, method, path string
+		want                     int
+	}{
+		{"user lists users", models.RoleUser, http.MethodGet, "/users", http.StatusForbidden},
+		{"user creates user", models.RoleUser, http.MethodPost, "/users", http.StatusForbidden},
+		{"user reads other", models.RoleUser, http.MethodGet, "/users/8", http.StatusForbidden},
+		{"user updates other", models.RoleUser, http.MethodPut, "/users/8", http.StatusForbidden},
+		{"user deletes other", models.RoleUser, http.MethodDelete, "/users/8", http.StatusForbidden},
+		{"user bad id", models.RoleUser, http.MethodGet, "/users/abc", http.StatusForbidden},
+		{"user reads self", models.RoleUser, http.MethodGet, "/users/7", http.StatusOK},
+		{"user updates self", models.RoleUser, http.MethodPut, "/users/7", http.StatusOK},
+		{"no role reads self", "", http.MethodGet, "/users/7", http.StatusOK},
+		{"no role lists users", "", http.MethodGet, "/users", http.StatusForbidden},
+		{"admin lists users", models.RoleAdmin, http.MethodGet, "/users", http.StatusOK},
+		{"admin creates user", models.RoleAdmin, http.MethodPost, "/users", http.StatusOK},
+		{"admin reads other", models.RoleAdmin, http.MethodGet, "/users/8", http.StatusOK},
+		{"admin deletes other", models.RoleAdmin, http.MethodDelete, "/users/8", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("X-Role", tc.role)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("%s %s as %q: want %d, got %d", tc.method, tc.path, tc.role, tc.want, w.Code)
+			}
+		})
+	}
+}
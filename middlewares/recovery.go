@@ -3,8 +3,9 @@
 package middlewares
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 
 	"github.com/gin-gonic/gin" //gin context and middleware support 
 )
@@ -20,7 +21,7 @@ func Recovery() gin.HandlerFunc {
 		//defer a function that recovers from panic if one happens during c.Next()
 		defer func() {
 			if r := recover(); r != nil { // if r is not nill , a panic occurred
-				log.Printf("[panic] %v", r) //logthe panic valuee
+				slog.ErrorContext(c.Request.Context(), "panic", "panic", r, "stack", string(debug.Stack())) //logthe panic valuee + where
 				c.AbortWithStatusJSON(http.StatusInternalServerError, //return 500 json 
 					gin.H{"error": "internal error"}) 
 			}
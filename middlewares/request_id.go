@@ -0,0 +1,31 @@
+// Request ID: accepted from X-Request-ID or generated, echoed back and stored in the request context.
+
+package middlewares
+
+import (
+	"regexp"
+
+	"HelmyTask/utils"
+	"HelmyTask/utils/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is read from the client/proxy and always set on the response.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps caller-supplied IDs short and log-safe (no spaces, quotes or newlines).
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID must run first so every later log line (slog and redislog) can carry the ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id, _ = utils.RandomToken(12) // 16 URL-safe chars.
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HelmyTask/middlewares"
+	"HelmyTask/utils/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID_AcceptsOrGenerates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.RequestID())
+	var seen string
+	r.GET("/", func(c *gin.Context) { seen = logging.RequestID(c.Request.Context()) })
+
+	cases := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"valid", "abc-123.def:9_x", true},
+		{"missing", "", false},
+		{"unsafe", "bad id\nwith newline", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(middlewares.RequestIDHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(middlewares.RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("header %q and context %q must match and be set", got, seen)
+			}
+			if tc.keep != (got == tc.header) {
+				t.Fatalf("header in %q, out %q (keep=%v)", tc.header, got, tc.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Per-request deadline carried by the request context.
+
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives every request a deadline of d (0 disables). It does not cut the response off:
+// DB and Redis calls made with c.Request.Context() fail once it passes and handlers answer 504.
+// Routes listed in exempt (gin full paths, e.g. a Server-Sent-Events stream) keep no deadline.
+func Timeout(d time.Duration, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, p := range exempt {
+		skip[p] = true
+	}
+	return func(c *gin.Context) {
+		if d <= 0 || skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
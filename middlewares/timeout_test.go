@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"HelmyTask/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout_SetsRequestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.Timeout(20 * time.Millisecond))
+	var got error
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done() // Stands in for a DB call honouring the context.
+		got = c.Request.Context().Err()
+		c.Status(http.StatusGatewayTimeout)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if !errors.Is(got, context.DeadlineExceeded) {
+		t.Fatalf("want deadline exceeded, got %v", got)
+	}
+}
+
+func TestTimeout_ZeroDisables(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.Timeout(0))
+	hasDeadline := true
+	r.GET("/", func(c *gin.Context) { _, hasDeadline = c.Request.Context().Deadline() })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if hasDeadline {
+		t.Fatal("want no deadline with timeout 0")
+	}
+}
+
+func TestTimeout_ExemptRouteHasNoDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.Timeout(time.Second, "/stream/:id"))
+	deadlines := map[string]bool{}
+	record := func(c *gin.Context) { _, deadlines[c.FullPath()] = c.Request.Context().Deadline() }
+	r.GET("/stream/:id", record)
+	r.GET("/other", record)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+	if deadlines["/stream/:id"] || !deadlines["/other"] {
+		t.Fatalf("want deadline only on /other, got %v", deadlines)
+	}
+}
@@ -0,0 +1,56 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"HelmyTask/middlewares"
+	"HelmyTask/utils/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_ContinuesW3CTraceContext(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := tracing.Install(exp, tracing.Config{ServiceName: "test"})
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middlewares.Tracing())
+	var inner trace.SpanContext
+	r.GET("/things/:id", func(c *gin.Context) {
+		inner = trace.SpanContextFromContext(c.Request.Context()) // Handlers see the request span.
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/things/7", nil)
+	req.Header.Set("traceparent", parent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("want 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.Name != "GET /things/:id" || s.SpanKind != trace.SpanKindServer {
+		t.Fatalf("span = %q kind %v", s.Name, s.SpanKind)
+	}
+	if got := s.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace ID not continued from traceparent: %s", got)
+	}
+	if s.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("parent span = %s", s.Parent.SpanID())
+	}
+	if s.Status.Code.String() != "Error" {
+		t.Fatalf("5xx should mark the span failed, got %v", s.Status.Code)
+	}
+	if inner.SpanID() != s.SpanContext.SpanID() || w.Header().Get("X-Trace-ID") != s.SpanContext.TraceID().String() {
+		t.Fatalf("request context / X-Trace-ID do not carry the span")
+	}
+}
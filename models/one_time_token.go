@@ -0,0 +1,24 @@
+// GORM model for single-use tokens (password reset, ...). Also the JSON shape stored in Redis.
+
+package models
+
+import "time"
+
+// Purposes of one-time tokens; a token is only valid for the purpose it was minted for.
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+	TokenPurposeMFAChallenge  = "mfa_challenge"
+)
+
+// OneTimeToken is a hashed, expiring token that can be consumed exactly once.
+type OneTimeToken struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	Purpose   string     `gorm:"size:32;index;not null" json:"purpose"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"token_hash"` // hex sha256 of the raw token
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	EmailHash string     `gorm:"size:64" json:"email_hash,omitempty"` // email_verify: sha256 of the address it was mailed to
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
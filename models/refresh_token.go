@@ -0,0 +1,18 @@
+// GORM model for opaque refresh tokens (also the JSON shape stored in Redis).
+
+package models
+
+import "time"
+
+// RefreshToken is one issued refresh token. Only the SHA-256 of the raw token is stored.
+// All tokens minted from the same login share a FamilyID so reuse can revoke the whole chain.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"token_hash"` // hex sha256 of the raw token
+	FamilyID  string     `gorm:"size:64;index;not null" json:"family_id"`        // shared by every rotation of one login
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`    // set once the token has been rotated
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // set when the family is revoked
+	CreatedAt time.Time  `json:"created_at"`
+}
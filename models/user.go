@@ -2,7 +2,11 @@
 
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 //user represents a user record in the database 
 //Gorm tags configure primary key , sizes and constrains
@@ -12,10 +16,22 @@ type User struct {
 	Name      string    `gorm:"size:120;not null" json:"name"` //amybe add uniqueIndex
 	Email     string    `gorm:"size:180;uniqueIndex;not null" json:"email"`
 	Password  string    `gorm:"size:255;not null" json:"-"` // hashed
+	Role      string    `gorm:"size:20;not null;default:user" json:"role"` // RoleAdmin | RoleUser
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // nil until the emailed token is confirmed
+	TOTPSecret    string `gorm:"size:64" json:"-"`    // base32 TOTP secret (pending until MFAEnabled)
+	MFAEnabled    bool   `gorm:"not null;default:false" json:"mfa_enabled"`
+	RecoveryCodes string `gorm:"size:1000" json:"-"`  // newline-separated bcrypt hashes of unused recovery codes
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"` // soft delete; purged after the retention period
 }
 
+// Roles understood by middlewares.RequireRole.
+const (
+	RoleAdmin = "admin" // full access to /users
+	RoleUser  = "user"  // may only manage itself
+)
+
 // DTOs (request/response)
 // RegisterRequest is the expected payload for the register endpoint.
 // Gin's binding tags add basic validation rules automatically.
@@ -29,11 +45,59 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	ClientIP string `json:"-"` // Filled by the handler; drives per-IP lockout.
 }
 
 //small resonse object hodl jwt token 
+//plus the opaque refresh token used to get a new pair without re-entering the password
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token            string     `json:"token"`
+	ExpiresAt        time.Time  `json:"expires_at"`                   // access token expiry
+	RefreshToken     string     `json:"refresh_token,omitempty"`      // empty if no refresh store is configured
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"` // refresh token expiry
+
+	// Two-step login: when MFARequired is true, Token is empty and MFAToken must be sent
+	// to /auth/mfa/verify with a TOTP or recovery code; ExpiresAt is then the challenge expiry.
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+//payload for POST /auth/mfa/verify (second login step)
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+//payload for MFA endpoints that only need a code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+//payload for disabling MFA: password and a current code
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+//response of TOTP enrollment; render ProvisioningURI as a QR code
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+//recovery codes are only ever shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+//optional payload for the logout endpoint; sending the refresh token revokes it too
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+//expected payload for the refresh endpoint
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 
@@ -44,6 +108,37 @@ type UpdateUserRequest struct {
 	Name *string `json:"name,omitempty"`
 	Email *string `json:"email,omitempty"`
 	Password *string `json:"password,omitempty"`
+	Role *string `json:"role,omitempty" binding:"omitempty,oneof=admin user"` // admin only
+}
+
+
+//payload for POST /me/password; the current password must be re-entered
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+
+//payload for POST /auth/password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+//payload for POST /auth/password/reset
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+
+//payload for POST /auth/verify-email (GET takes ?token=)
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+//payload for POST /auth/verify-email/resend
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
 }
 
 
@@ -53,13 +148,55 @@ type ListUserQuery struct {
 Page int `form:"page"` // Page number (1-based). We'll default in handler/service if 0.
 Limit int `form:"limit"` // Page size (items per page). We'll clamp sane defaults.
 
+// Filters; all optional and combined with AND. Text matches are case-insensitive substrings.
+Q           string    `form:"q"`     // name OR email contains
+Name        string    `form:"name"`  // name contains
+Email       string    `form:"email"` // email contains
+Role        string    `form:"role" binding:"omitempty,oneof=admin user"`
+Status      string    `form:"status" binding:"omitempty,oneof=active verified unverified deleted"` // default active
+CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"` // created_at >= (RFC 3339)
+CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`   // created_at <  (RFC 3339)
+
+// Sort is a comma-separated list of fields, "-" prefix for descending, e.g. "-created_at,name".
+// Allowed: id, name, email, role, created_at, updated_at, deleted_at. Default "id".
+Sort string `form:"sort"`
+
+// Cursor (keyset) paging: start with paging=cursor, then follow next_cursor/prev_cursor.
+// Page is ignored in this mode and the total is only counted when asked for.
+Paging    string `form:"paging" binding:"omitempty,oneof=offset cursor"`
+Cursor    string `form:"cursor"`     // opaque, signed; implies paging=cursor
+WithTotal bool   `form:"with_total"` // also count matches in cursor mode
 }
 
+// Paging modes for ListUserQuery.Paging.
+const (
+	PagingOffset = "offset" // page/limit with COUNT(*) (default)
+	PagingCursor = "cursor" // keyset with next/prev cursors
+)
+
+// UserCursor is the decoded form of the opaque next_cursor/prev_cursor strings.
+type UserCursor struct {
+	Sort     string   `json:"s"`           // sort the cursor belongs to
+	Values   []string `json:"v"`           // boundary row's sort values (id last)
+	Backward bool     `json:"b,omitempty"` // page before the boundary (prev_cursor)
+}
+
+// User status filters for ListUserQuery.Status.
+const (
+	UserStatusActive     = "active"     // not deleted (default)
+	UserStatusVerified   = "verified"   // active with a confirmed email
+	UserStatusUnverified = "unverified" // active, email not confirmed yet
+	UserStatusDeleted    = "deleted"    // soft-deleted, not purged yet
+)
+
 
 //PageUsers-response envelope for list endpoint
 type PagedUsers struct {
 	Items []User `json:"items"` // Current page of users.
-	Total int64  `json:"total"` // Total number of users in DB (for pagination UIs).
-	Page  int    `json:"page"`  // Current page number (1-based).
+	Total *int64 `json:"total,omitempty"` // Total matching users (always in offset mode, on request in cursor mode).
+	Page  int    `json:"page,omitempty"`  // Current page number (1-based; offset mode only).
 	Limit int    `json:"limit"` // Page size used.
+
+	NextCursor string `json:"next_cursor,omitempty"` // cursor mode: following page, if any
+	PrevCursor string `json:"prev_cursor,omitempty"` // cursor mode: preceding page, if any
 }
\ No newline at end of file
@@ -0,0 +1,100 @@
+// Storage for single-use tokens (password reset, ...). Redis when available, DB otherwise.
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"HelmyTask/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// OneTimeTokenRepository stores hashed tokens that can be consumed once before they expire.
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, t *models.OneTimeToken) error
+	// Consume atomically marks the token as used and returns it.
+	// Unknown, used or expired tokens return gorm.ErrRecordNotFound.
+	Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error)
+}
+
+// ---------------- GORM ----------------
+
+type oneTimeTokenRepo struct{ db *gorm.DB }
+
+// NewOneTimeTokenRepository stores tokens in the one_time_tokens table.
+func NewOneTimeTokenRepository(db *gorm.DB) OneTimeTokenRepository {
+	return &oneTimeTokenRepo{db: db}
+}
+
+func (r *oneTimeTokenRepo) Create(ctx context.Context, t *models.OneTimeToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *oneTimeTokenRepo) Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error) {
+	var t models.OneTimeToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// Conditional update wins at most once even under concurrency.
+		res := tx.Model(&models.OneTimeToken{}).
+			Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, now).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("token_hash = ?", hash).First(&t).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ---------------- Redis ----------------
+
+// Keys: otk:<purpose>:<hash> → JSON of models.OneTimeToken, expiring with the token.
+type redisOneTimeTokenRepo struct{ rdb *redis.Client }
+
+// NewRedisOneTimeTokenRepository stores tokens as expiring Redis keys.
+func NewRedisOneTimeTokenRepository(rdb *redis.Client) OneTimeTokenRepository {
+	return &redisOneTimeTokenRepo{rdb: rdb}
+}
+
+func (r *redisOneTimeTokenRepo) key(purpose, hash string) string {
+	return "otk:" + purpose + ":" + hash
+}
+
+func (r *redisOneTimeTokenRepo) Create(ctx context.Context, t *models.OneTimeToken) error {
+	t.CreatedAt = time.Now()
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, r.key(t.Purpose, t.TokenHash), b, time.Until(t.ExpiresAt)).Err()
+}
+
+// Consume uses GETDEL so the key disappears the moment it is read.
+func (r *redisOneTimeTokenRepo) Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error) {
+	val, err := r.rdb.GetDel(ctx, r.key(purpose, hash)).Result()
+	if err == redis.Nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t models.OneTimeToken
+	if err := json.Unmarshal([]byte(val), &t); err != nil {
+		return nil, err
+	}
+	if time.Now().After(t.ExpiresAt) { // Belt and braces; TTL should have removed it.
+		return nil, gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return &t, nil
+}
@@ -0,0 +1,154 @@
+// Storage for opaque refresh tokens. Two implementations share one interface:
+// Redis (preferred, keys expire on their own) and GORM (used when Redis is absent).
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"HelmyTask/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository is what the service needs to rotate refresh tokens safely.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *models.RefreshToken) error                  // Persist a newly issued token.
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) // Load by sha256; ErrRecordNotFound if unknown/expired.
+	MarkUsed(ctx context.Context, hash string) (bool, error)                   // Atomically flag as rotated; false if it was already used.
+	RevokeFamily(ctx context.Context, familyID string) error                   // Revoke every token of one login chain.
+	RevokeUser(ctx context.Context, userID uint) error                         // Revoke every family of a user (logout everywhere).
+}
+
+// ---------------- GORM ----------------
+
+type refreshTokenRepo struct{ db *gorm.DB }
+
+// NewRefreshTokenRepository stores refresh tokens in the refresh_tokens table.
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepo{db: db}
+}
+
+func (r *refreshTokenRepo) Create(ctx context.Context, t *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *refreshTokenRepo) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// MarkUsed only updates rows that were not used yet, so two concurrent refreshes cannot both win.
+func (r *refreshTokenRepo) MarkUsed(ctx context.Context, hash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND used_at IS NULL", hash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *refreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepo) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ---------------- Redis ----------------
+
+// Key layout:
+//
+//	refresh:<hash>                 JSON of models.RefreshToken, expires with the token
+//	refresh:used:<hash>            set once the token was rotated
+//	refresh:family:<id>:revoked    set when the family is revoked (lives for ttl)
+//	refresh:user:<uid>             SET of family IDs issued to a user
+type redisRefreshTokenRepo struct {
+	rdb *redis.Client
+	ttl time.Duration // max refresh lifetime; bounds how long revocation markers are kept
+}
+
+// NewRedisRefreshTokenRepository stores refresh tokens as Redis keys with TTLs.
+func NewRedisRefreshTokenRepository(rdb *redis.Client, ttl time.Duration) RefreshTokenRepository {
+	return &redisRefreshTokenRepo{rdb: rdb, ttl: ttl}
+}
+
+func (r *redisRefreshTokenRepo) key(hash string) string     { return "refresh:" + hash }
+func (r *redisRefreshTokenRepo) usedKey(hash string) string { return "refresh:used:" + hash }
+func (r *redisRefreshTokenRepo) familyKey(id string) string {
+	return "refresh:family:" + id + ":revoked"
+}
+func (r *redisRefreshTokenRepo) userKey(id uint) string { return fmt.Sprintf("refresh:user:%d", id) }
+
+func (r *redisRefreshTokenRepo) Create(ctx context.Context, t *models.RefreshToken) error {
+	t.CreatedAt = time.Now()
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := r.rdb.Set(ctx, r.key(t.TokenHash), b, time.Until(t.ExpiresAt)).Err(); err != nil {
+		return err
+	}
+	// Index the family under the user so RevokeUser can find it.
+	_ = r.rdb.SAdd(ctx, r.userKey(t.UserID), t.FamilyID).Err()
+	_ = r.rdb.Expire(ctx, r.userKey(t.UserID), r.ttl).Err()
+	return nil
+}
+
+func (r *redisRefreshTokenRepo) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	val, err := r.rdb.Get(ctx, r.key(hash)).Result()
+	if err == redis.Nil {
+		return nil, gorm.ErrRecordNotFound // Same sentinel as the DB repo so callers can use IsNotFound.
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t models.RefreshToken
+	if err := json.Unmarshal([]byte(val), &t); err != nil {
+		return nil, err
+	}
+	// Fold the side keys back into the record.
+	if n, _ := r.rdb.Exists(ctx, r.usedKey(hash)).Result(); n > 0 {
+		now := time.Now()
+		t.UsedAt = &now
+	}
+	if n, _ := r.rdb.Exists(ctx, r.familyKey(t.FamilyID)).Result(); n > 0 {
+		now := time.Now()
+		t.RevokedAt = &now
+	}
+	return &t, nil
+}
+
+// MarkUsed relies on SETNX so only the first caller gets true.
+func (r *redisRefreshTokenRepo) MarkUsed(ctx context.Context, hash string) (bool, error) {
+	return r.rdb.SetNX(ctx, r.usedKey(hash), 1, r.ttl).Result()
+}
+
+func (r *redisRefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.rdb.Set(ctx, r.familyKey(familyID), 1, r.ttl).Err()
+}
+
+func (r *redisRefreshTokenRepo) RevokeUser(ctx context.Context, userID uint) error {
+	families, err := r.rdb.SMembers(ctx, r.userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, f := range families {
+		if err := r.RevokeFamily(ctx, f); err != nil {
+			return err
+		}
+	}
+	return r.rdb.Del(ctx, r.userKey(userID)).Err()
+}
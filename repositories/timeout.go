@@ -0,0 +1,62 @@
+package repositories // Per-statement deadline for every repository query.
+
+import ( // Imports for the timeout plugin.
+	"context" // Deadline on the statement context.
+	"errors"  // Collect registration errors.
+	"time"    // Timeout type.
+
+	"gorm.io/gorm" // Callback API.
+)
+
+// QueryTimeout caps each GORM statement at the given duration (on top of any request deadline).
+// Enable with db.Use(repositories.QueryTimeout(5 * time.Second)).
+type QueryTimeout time.Duration
+
+const timeoutKey = "timeout:parent" // Instance key holding the original context + cancel func.
+
+type statementTimeout struct { // What finish needs to undo start.
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// Name implements gorm.Plugin.
+func (QueryTimeout) Name() string { return "query_timeout" }
+
+// Initialize implements gorm.Plugin. Row/Rows are skipped: their result is read after the
+// callbacks return, so cancelling in an after hook would break the scan.
+func (q QueryTimeout) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("timeout:before_create", q.start),
+		cb.Create().After("gorm:create").Register("timeout:after_create", finish),
+		cb.Query().Before("gorm:query").Register("timeout:before_query", q.start),
+		cb.Query().After("gorm:query").Register("timeout:after_query", finish),
+		cb.Update().Before("gorm:update").Register("timeout:before_update", q.start),
+		cb.Update().After("gorm:update").Register("timeout:after_update", finish),
+		cb.Delete().Before("gorm:delete").Register("timeout:before_delete", q.start),
+		cb.Delete().After("gorm:delete").Register("timeout:after_delete", finish),
+		cb.Raw().Before("gorm:raw").Register("timeout:before_raw", q.start),
+		cb.Raw().After("gorm:raw").Register("timeout:after_raw", finish),
+	)
+}
+
+// start swaps the statement context for one with the deadline.
+func (q QueryTimeout) start(tx *gorm.DB) {
+	if q <= 0 {
+		return
+	}
+	parent := tx.Statement.Context
+	ctx, cancel := context.WithTimeout(parent, time.Duration(q))
+	tx.Statement.Context = ctx
+	tx.InstanceSet(timeoutKey, statementTimeout{parent: parent, cancel: cancel})
+}
+
+// finish releases the timer and restores the original context for the rest of the chain (commit, hooks).
+func finish(tx *gorm.DB) {
+	if v, ok := tx.InstanceGet(timeoutKey); ok {
+		if st, ok := v.(statementTimeout); ok {
+			st.cancel()
+			tx.Statement.Context = st.parent
+		}
+	}
+}
@@ -6,7 +6,12 @@ package repositories
 
 import (
 	"HelmyTask/models" // Import our User model to map results.
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm" // GORM DB type is injected so repos are testable/mocked.
 )
@@ -14,13 +19,19 @@ import (
 // UserRepository defines the operations our service layer expects.
 // Depending on interfaces (not concrete types) helps testability and swapping implementations.
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByEmail(email string) (*models.User, error)
-	FindByID(id uint) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
 	//ADDIGN  THE reamin CRUD
-	Update(user *models.User) error
-	Delete(id uint) error                                 // Delete by primary key.
-	List(offset, limit int) ([]models.User, int64, error) // Page through users + total count.
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error                                 // Soft delete by primary key (sets deleted_at).
+	List(ctx context.Context, q models.ListUserQuery, p Page) ([]models.User, int64, error) // Filtered/sorted page (offset or keyset) + optional total.
+
+	// Soft-delete lifecycle.
+	EmailExists(ctx context.Context, email string) (bool, error)                      // Any row with this email, deleted ones included (unique index).
+	Restore(ctx context.Context, id uint) (*models.User, error)                       // Clear deleted_at; ErrRecordNotFound if not deleted.
+	ListDeleted(ctx context.Context, offset, limit int) ([]models.User, int64, error) // Page through soft-deleted users.
+	PurgeDeleted(ctx context.Context, before time.Time) ([]models.User, error)        // Permanently remove users deleted before the cutoff (and their token rows).
 
 }
 
@@ -37,36 +48,36 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // Create inserts a new user row using GORM's Create method.
-func (r *userRepo) Create(u *models.User) error {
-	return r.db.Create(u).Error // .Error exposes any DB error to caller.
+func (r *userRepo) Create(ctx context.Context, u *models.User) error {
+	return r.db.WithContext(ctx).Create(u).Error // .Error exposes any DB error to caller.
 }
 
 // FindByEmail queries for a user with the given email.
 // We use a parameterized query (WHERE email = ?) which GORM compiles safely for the dialect.
-func (r *userRepo) FindByEmail(email string) (*models.User, error) {
+func (r *userRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var u models.User
-	if err := r.db.Where("email = ?", email).First(&u).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&u).Error; err != nil {
 		return nil, err
 	}
 	return &u, nil // Return pointer to the found user.
 }
 
-func (r *userRepo) FindByID(id uint) (*models.User, error) {
+func (r *userRepo) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var u models.User
-	if err := r.db.First(&u, id).Error; err != nil { // First(&u, id) loads where primary key = id.
+	if err := r.db.WithContext(ctx).First(&u, id).Error; err != nil { // First(&u, id) loads where primary key = id.
 		return nil, err
 	}
 	return &u, nil
 }
 
 // Update saves fields on an existing user (assumes u has valid ID).
-func (r *userRepo) Update(u *models.User) error {
-	return r.db.Save(u).Error // Save writes all fields; for partial updates use Select/Omit.
+func (r *userRepo) Update(ctx context.Context, u *models.User) error {
+	return r.db.WithContext(ctx).Save(u).Error // Save writes all fields; for partial updates use Select/Omit.
 }
 
-// Delete removes a user row by primary key. If not found, return ErrRecordNotFound.
-func (r *userRepo) Delete(id uint) error {
-	res := r.db.Delete(&models.User{}, id) // Soft delete if GORM soft-deletes are enabled; here it's hard delete.
+// Delete soft-deletes a user row by primary key. If not found, return ErrRecordNotFound.
+func (r *userRepo) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&models.User{}, id) // User has gorm.DeletedAt, so this only stamps deleted_at.
 	if res.Error != nil {
 		return res.Error                   // Return DB error if any.
 	}
@@ -76,26 +87,290 @@ func (r *userRepo) Delete(id uint) error {
 	return nil
 }
 
-// List returns a page of users and the total count (for pagination UIs).
-func (r *userRepo) List(offset, limit int) ([]models.User, int64, error) {
+// ErrInvalidSort is returned when ListUserQuery.Sort names a field outside the whitelist.
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// sortColumns whitelists sortable fields; values are the column names used in ORDER BY.
+var sortColumns = map[string]string{
+	"id": "id", "name": "name", "email": "email", "role": "role",
+	"created_at": "created_at", "updated_at": "updated_at", "deleted_at": "deleted_at",
+}
+
+// likeEscaper escapes LIKE wildcards with '!' (an ESCAPE char every supported driver accepts
+// without quoting trouble, unlike backslash on MySQL).
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// contains builds a lower-cased "%term%" LIKE pattern.
+func contains(term string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+}
+
+// filtered applies ListUserQuery filters. LOWER(col) LIKE works the same on
+// MySQL, PostgreSQL, SQLite and SQL Server, so search is case-insensitive everywhere.
+func (r *userRepo) filtered(ctx context.Context, q models.ListUserQuery) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&models.User{})
+	switch q.Status {
+	case models.UserStatusDeleted:
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	case models.UserStatusVerified:
+		db = db.Where("email_verified_at IS NOT NULL")
+	case models.UserStatusUnverified:
+		db = db.Where("email_verified_at IS NULL")
+	}
+	if q.Q != "" {
+		p := contains(q.Q)
+		db = db.Where("(LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!')", p, p)
+	}
+	if q.Name != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '!'", contains(q.Name))
+	}
+	if q.Email != "" {
+		db = db.Where("LOWER(email) LIKE ? ESCAPE '!'", contains(q.Email))
+	}
+	if q.Role != "" {
+		db = db.Where("role = ?", q.Role)
+	}
+	if !q.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", q.CreatedFrom)
+	}
+	if !q.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", q.CreatedTo)
+	}
+	return db
+}
+
+// ErrInvalidCursor is returned for cursors that do not fit the current sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects which slice of the result List returns.
+type Page struct {
+	Offset int                // offset mode (ignored when After is set)
+	Limit  int                // max rows
+	After  *models.UserCursor // keyset mode: rows after the boundary row (before it when Backward)
+	Count  bool               // also run COUNT(*) for the total
+}
+
+// sortKey is one parsed ORDER BY term.
+type sortKey struct {
+	col  string
+	desc bool
+}
+
+// parseSort turns "-created_at,name" into keys for created_at DESC, name ASC, id ASC.
+// id is always appended as a tie-breaker so pages are stable (and keysets unique).
+func parseSort(sort string) ([]sortKey, error) {
+	var keys []sortKey
+	hasID := false
+	for _, f := range strings.Split(sort, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		desc := false
+		if strings.HasPrefix(f, "-") {
+			desc, f = true, f[1:]
+		} else if strings.HasPrefix(f, "+") {
+			f = f[1:]
+		}
+		col, ok := sortColumns[f]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSort, f)
+		}
+		hasID = hasID || col == "id"
+		keys = append(keys, sortKey{col: col, desc: desc})
+	}
+	if !hasID {
+		keys = append(keys, sortKey{col: "id"})
+	}
+	return keys, nil
+}
+
+// orderBy renders keys as an ORDER BY clause; reverse flips every direction (for prev pages).
+func orderBy(keys []sortKey, reverse bool) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.desc != reverse {
+			dir = "DESC"
+		}
+		parts[i] = k.col + " " + dir
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keyset builds "rows strictly after the boundary in this order":
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?), with < for DESC keys.
+// Expanded instead of row-value syntax because SQL Server has no (a, b) > (?, ?).
+func keyset(keys []sortKey, c *models.UserCursor) (string, []interface{}, error) {
+	if len(c.Values) != len(keys) {
+		return "", nil, ErrInvalidCursor
+	}
+	vals := make([]interface{}, len(keys))
+	for i, k := range keys {
+		v, err := cursorValue(k.col, c.Values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		vals[i] = v
+	}
+	var (
+		ors  []string
+		args []interface{}
+	)
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].col+" = ?")
+			args = append(args, vals[j])
+		}
+		op := ">"
+		if k.desc != c.Backward {
+			op = "<"
+		}
+		ands = append(ands, k.col+" "+op+" ?")
+		args = append(args, vals[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args, nil
+}
+
+// cursorValue converts a cursor string back to the column's Go type.
+func cursorValue(col, s string) (interface{}, error) {
+	switch col {
+	case "id":
+		return strconv.ParseUint(s, 10, 64)
+	case "created_at", "updated_at", "deleted_at":
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return s, nil
+	}
+}
+
+// NewUserCursor captures u's sort values so the next/prev page can start after/before it.
+func NewUserCursor(u *models.User, sort string, backward bool) (*models.UserCursor, error) {
+	keys, err := parseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	c := &models.UserCursor{Sort: sort, Backward: backward, Values: make([]string, len(keys))}
+	for i, k := range keys {
+		switch k.col {
+		case "id":
+			c.Values[i] = strconv.FormatUint(uint64(u.ID), 10)
+		case "name":
+			c.Values[i] = u.Name
+		case "email":
+			c.Values[i] = u.Email
+		case "role":
+			c.Values[i] = u.Role
+		case "created_at":
+			c.Values[i] = u.CreatedAt.Format(time.RFC3339Nano)
+		case "updated_at":
+			c.Values[i] = u.UpdatedAt.Format(time.RFC3339Nano)
+		case "deleted_at":
+			if !u.DeletedAt.Valid { // NULLs cannot be compared; only sortable among deleted users.
+				return nil, fmt.Errorf("%w: deleted_at needs status=deleted in cursor mode", ErrInvalidSort)
+			}
+			c.Values[i] = u.DeletedAt.Time.Format(time.RFC3339Nano)
+		}
+	}
+	return c, nil
+}
+
+// List returns a filtered, sorted page of users and, if p.Count, the total count of matches.
+// With p.After set it seeks past the cursor (keyset) instead of using OFFSET, so deep pages
+// stay cheap and rows inserted meanwhile do not shift the page. Backward pages come back
+// in reverse order; the caller flips them.
+func (r *userRepo) List(ctx context.Context, q models.ListUserQuery, p Page) ([]models.User, int64, error) {
 	var (
 		items []models.User // Slice to collect this page.
-		total int64         // Total rows in table.
+		total int64         // Total matching rows.
 	)
-	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
-		return nil, 0, err // Counting failed → return error.
-	}
-	if err := r.db.
-		Limit(limit).      // Restrict page size.
-		Offset(offset).    // Start from offset (page-1)*limit.
-		Order("id ASC").   // Deterministic ordering.
-		Find(&items).      // Load rows into slice.
-		Error; err != nil {
+	keys, err := parseSort(q.Sort)
+	if err != nil {
+		return nil, 0, err // Unknown sort field → caller maps to 400.
+	}
+	if p.Count {
+		if err := r.filtered(ctx, q).Count(&total).Error; err != nil {
+			return nil, 0, err // Counting failed → return error.
+		}
+	}
+	db := r.filtered(ctx, q).Limit(p.Limit) // Restrict page size.
+	if p.After != nil {
+		where, args, err := keyset(keys, p.After)
+		if err != nil {
+			return nil, 0, err
+		}
+		db = db.Where(where, args...).Order(orderBy(keys, p.After.Backward)) // Seek past the boundary row.
+	} else {
+		db = db.Offset(p.Offset).Order(orderBy(keys, false)) // Start from offset (page-1)*limit.
+	}
+	if err := db.Find(&items).Error; err != nil {
 		return nil, 0, err // Find failed → return error.
 	}
 	return items, total, nil // Return slice and total count.
 }
 
+// EmailExists also sees soft-deleted rows: their email still occupies the unique index until purged.
+func (r *userRepo) EmailExists(ctx context.Context, email string) (bool, error) {
+	var n int64
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Restore brings a soft-deleted user back.
+func (r *userRepo) Restore(ctx context.Context, id uint) (*models.User, error) {
+	res := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound // Unknown or not deleted.
+	}
+	return r.FindByID(ctx, id)
+}
+
+// ListDeleted returns a page of soft-deleted users, most recently deleted first.
+func (r *userRepo) ListDeleted(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	return r.List(ctx, models.ListUserQuery{Status: models.UserStatusDeleted, Sort: "-deleted_at"}, Page{Offset: offset, Limit: limit, Count: true})
+}
+
+// PurgeDeleted hard-deletes users soft-deleted before the cutoff together with their
+// refresh_tokens and one_time_tokens rows. It returns the removed users (ID and email only)
+// so the caller can drop cache and Redis state.
+func (r *userRepo) PurgeDeleted(ctx context.Context, before time.Time) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.WithContext(ctx).Unscoped().Select("id", "email").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.OneTimeToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, ids).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // Helper: IsNotFound checks GORM's "record not found" sentinel.
 func IsNotFound(err error) bool {
 	return errors.Is(err, gorm.ErrRecordNotFound) // True if wrapped or direct ErrRecordNotFound.
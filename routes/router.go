@@ -1,44 +1,89 @@
 package routes // Router setup layer.
 
 import ( // Imports used in the router.
-	"time" // For JWT expiration type.
+	"time" // For JWT expiration + request timeout types.
 
 	"HelmyTask/handlers" // User handler constructor.
 	"HelmyTask/middlewares" // Logging & recovery & auth middlewares.
+	"HelmyTask/models" // Role names.
 	"HelmyTask/services" // User service interface.
+	"HelmyTask/utils/jwtauth" // JWT validator/signer.
 
 	"github.com/gin-gonic/gin" // Gin router.
 )
 
 // Setup attaches middlewares and registers all endpoints.
-func Setup(r *gin.Engine, svc services.UserService, jwtSecret string, jwtExp time.Duration) {
+// limits throttles the public auth endpoints per IP and the protected group per user/IP/API key.
+// requestTimeout bounds each request's context (0 disables); the log tail stream is exempt.
+func Setup(r *gin.Engine, svc services.UserService, tokens *jwtauth.Validator, jwtExp, requestTimeout time.Duration, limits middlewares.RateLimits, health *handlers.HealthHandler, logs *handlers.LogHandler) {
 	// Attach standard middlewares globally.
-	r.Use(middlewares.RequestLogger(), middlewares.Recovery()) // Access log + panic recovery.
+	r.Use(middlewares.RequestID(), middlewares.Tracing(), middlewares.RequestLogger(), middlewares.Metrics(), middlewares.Recovery(), middlewares.Timeout(requestTimeout, logTailPath)) // Request ID + trace span + access log + Prometheus + panic recovery + deadline.
+
+	// Probes for orchestrators/load balancers (outside /api/v1 and never rate limited).
+	r.GET("/healthz", health.Liveness) // Process alive.
+	r.GET("/readyz", health.Readiness) // DB + Redis reachable; 503 otherwise or while shutting down.
 
 	// Swagger (if you have docs/swagger.yaml); serves static file at /swagger.yaml.
 	r.StaticFile("/swagger.yaml", "./docs/swagger.yaml")
 
+	// Public verification keys so other services can check our tokens without the secret.
+	r.GET("/.well-known/jwks.json", handlers.JWKS(tokens.Keys()))
+
 	// Group API under /api/v1 for versioning.
 	api := r.Group("/api/v1")
 
 	// Create the user handler (injecting service + JWT parameters).
-	uh := handlers.NewUserHandler(svc, jwtSecret, jwtExp)
+	uh := handlers.NewUserHandler(svc, tokens, jwtExp) // tokens signs with the same policy Auth verifies.
 
-	// Public auth endpoints (no JWT required).
-	api.POST("/auth/register", uh.Register) // Register new user.
-	api.POST("/auth/login", uh.Login) // Login and get JWT.
+	// Public auth endpoints (no JWT required; stricter per-IP rate limit against credential stuffing).
+	public := api.Group("/", middlewares.RateLimit(limits.Limiter, limits.Auth))
+	public.POST("/auth/register", uh.Register) // Register new user.
+	public.POST("/auth/login", uh.Login) // Login and get JWT.
+	public.POST("/auth/refresh", uh.Refresh) // Rotate refresh token -> new JWT pair.
+	public.POST("/auth/password/forgot", uh.ForgotPassword) // Email a reset token.
+	public.POST("/auth/password/reset", uh.ResetPassword) // Set new password with the token.
+	public.POST("/auth/mfa/verify", uh.VerifyMFA) // Second login step (TOTP or recovery code).
+	public.GET("/auth/verify-email", uh.VerifyEmail) // Confirm email (link-friendly).
+	public.POST("/auth/verify-email", uh.VerifyEmail) // Confirm email (JSON body).
+	public.POST("/auth/verify-email/resend", uh.ResendVerification) // Re-send verification email (throttled).
 
 	// Protected group (requires valid Authorization: Bearer <token>).
 	protected := api.Group("/")
-	protected.Use(middlewares.Auth(jwtSecret)) // JWT auth middleware.
+	protected.Use(middlewares.Auth(tokens, svc)) // JWT auth middleware (svc answers revocation checks).
+	protected.Use(middlewares.RateLimit(limits.Limiter, limits.API)) // After Auth so limits can key by user.
+
+	// Session management.
+	protected.POST("/auth/logout", uh.Logout) // Revoke this token (+ refresh token if sent).
+	protected.POST("/auth/logout-all", uh.LogoutAll) // Revoke every session of the caller.
 
-	// "Me" endpoint (current user).
-	protected.GET("/me", uh.GetUser) // You could point to a dedicated 'Me' handler; here we reuse GetUser with context in your baseline.
+	// "Me" endpoints (current user, resolved from the token; no ID needed).
+	protected.GET("/me", uh.GetMe) // Read self.
+	protected.PATCH("/me", uh.UpdateMe) // Update name/email.
+	protected.DELETE("/me", uh.DeleteMe) // Close own account.
+	protected.POST("/me/password", uh.ChangePassword) // Change password (revokes all sessions).
 
-	// RESTful CRUD for users (admin-style).
-	protected.POST("/users", uh.CreateUser) // Create
-	protected.GET("/users", uh.ListUsers) // List (paginated)
-	protected.GET("/users/:id", uh.GetUser) // Read (one)
-	protected.PUT("/users/:id", uh.UpdateUser) // Update (partial)
-	protected.DELETE("/users/:id", uh.DeleteUser) // Delete
+	// Two-factor authentication (TOTP) for the current user.
+	protected.POST("/me/mfa/totp/enroll", uh.EnrollTOTP) // Start setup: secret + QR URI.
+	protected.POST("/me/mfa/totp/confirm", uh.ConfirmTOTP) // Enable with first code; returns recovery codes.
+	protected.POST("/me/mfa/totp/disable", uh.DisableTOTP) // Disable (password + code).
+	protected.POST("/me/mfa/recovery-codes", uh.RegenerateRecoveryCodes) // New recovery codes.
+
+	// RESTful CRUD for users: collection is admin-only, single user is admin or self.
+	admin := middlewares.RequireRole(models.RoleAdmin)
+	selfOrAdmin := middlewares.RequireSelfOrRole("id", models.RoleAdmin)
+	protected.POST("/users", admin, uh.CreateUser) // Create
+	protected.GET("/users", admin, uh.ListUsers) // List (paginated)
+	protected.GET("/users/deleted", admin, uh.ListDeletedUsers) // Soft-deleted users (restorable)
+	protected.GET("/users/:id", selfOrAdmin, uh.GetUser) // Read (one)
+	protected.PUT("/users/:id", selfOrAdmin, uh.UpdateUser) // Update (partial)
+	protected.DELETE("/users/:id", selfOrAdmin, uh.DeleteUser) // Delete (soft)
+	protected.POST("/users/:id/restore", admin, uh.RestoreUser) // Undo a delete
+	protected.POST("/users/:id/unlock", admin, uh.UnlockUser) // Lift a login lockout
+
+	// Application log viewer (entries written by redislog), admin-only.
+	protected.GET("/admin/logs", admin, logs.ListLogs) // Filtered, paged, newest first
+	protected.GET("/admin/logs/tail", admin, logs.TailLogs) // Live stream (Server-Sent Events)
 }
+
+// logTailPath is the SSE route; it stays open indefinitely, so no request deadline applies.
+const logTailPath = "/api/v1/admin/logs/tail"
@@ -0,0 +1,132 @@
+package services // Email verification use-cases (same userService, split out for readability).
+
+import ( // Imports for verification.
+	"context" // Redis throttle key.
+	"errors" // Domain errors.
+	"fmt" // Log/meta formatting.
+	"strings" // Case-insensitive address binding.
+	"time" // TTLs.
+
+	"HelmyTask/models" // User + token models.
+	"HelmyTask/repositories" // IsNotFound helper.
+	"HelmyTask/utils" // Token helpers.
+)
+
+// defaultVerifyTTL and defaultVerifyResendInterval are used when the matching options are not supplied.
+const (
+	defaultVerifyTTL            = 24 * time.Hour
+	defaultVerifyResendInterval = time.Minute
+)
+
+// Verification errors.
+var (
+	ErrInvalidVerifyToken = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified   = errors.New("email not verified")
+)
+
+// WithRequireVerifiedEmail blocks Login for accounts that have not confirmed their email.
+func WithRequireVerifiedEmail(require bool) Option {
+	return func(s *userService) { s.requireVerified = require }
+}
+
+// WithEmailVerifyTTL overrides how long a verification token stays valid.
+func WithEmailVerifyTTL(ttl time.Duration) Option {
+	return func(s *userService) { s.verifyTTL = ttl }
+}
+
+// WithVerifyResendInterval sets the minimum gap between two verification emails for one user.
+func WithVerifyResendInterval(d time.Duration) Option {
+	return func(s *userService) { s.verifyResendEvery = d }
+}
+
+// sendVerification mints a verification token for u and mails it.
+func (s *userService) sendVerification(ctx context.Context, u *models.User) error {
+	if s.oneTime == nil { // No token store → verification is not possible.
+		return errors.New("email verification unavailable")
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.oneTime.Create(ctx, &models.OneTimeToken{
+		Purpose:   models.TokenPurposeEmailVerify,
+		TokenHash: utils.HashToken(raw),
+		UserID:    u.ID,
+		EmailHash: verifyEmailHash(u.Email), // Bound to this address; a later email change voids it.
+		ExpiresAt: time.Now().Add(s.verifyTTL),
+	}); err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Confirm your email address with this token (valid for %s):\n\n%s\n", s.verifyTTL, raw)
+	if err := s.mailer.Send(u.Email, "Verify your email", body); err != nil {
+		return err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "verification email sent", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return nil
+}
+
+// verifyEmailHash is what a verification token stores about the address it was mailed to.
+func verifyEmailHash(email string) string { return utils.HashToken(strings.ToLower(email)) }
+
+// VerifyEmail consumes a verification token and stamps EmailVerifiedAt. The token only counts
+// for the address it was mailed to: after an email change, older tokens are rejected.
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	if s.oneTime == nil {
+		return nil, ErrInvalidVerifyToken
+	}
+	t, err := s.oneTime.Consume(ctx, models.TokenPurposeEmailVerify, utils.HashToken(token))
+	if err != nil {
+		if s.log != nil && !repositories.IsNotFound(err) { s.log.ErrorContext(ctx, "verify email consume error", map[string]string{"err": err.Error()}) }
+		return nil, ErrInvalidVerifyToken
+	}
+	u, err := s.repo.FindByID(ctx, t.UserID)
+	if err != nil {
+		return nil, ErrInvalidVerifyToken
+	}
+	if t.EmailHash != verifyEmailHash(u.Email) { // Mailed to a previous address (or minted before binding).
+		if s.log != nil { s.log.WarnContext(ctx, "verify email address mismatch", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		return nil, ErrInvalidVerifyToken
+	}
+	if u.EmailVerifiedAt == nil { // Idempotent if verified meanwhile.
+		now := time.Now()
+		u.EmailVerifiedAt = &now
+		if err := s.repo.Update(ctx, u); err != nil {
+			return nil, err
+		}
+		s.invalidateUserCache(ctx, u.ID) // Drop stale cached copy.
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "verify email success", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return u, nil
+}
+
+// ResendVerification mails a fresh token, at most once per resend interval.
+// Unknown, already verified and throttled requests all return nil so accounts cannot be probed.
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err != nil || u.EmailVerifiedAt != nil {
+		return nil
+	}
+	ok, err := s.allowResend(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if s.log != nil { s.log.WarnContext(ctx, "verification resend throttled", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		return nil // A distinct answer would reveal a registered, unverified address.
+	}
+	return s.sendVerification(ctx, u)
+}
+
+// allowResend claims the per-user resend slot: SETNX in Redis, or an in-process map without Redis.
+func (s *userService) allowResend(ctx context.Context, userID uint) (bool, error) {
+	if s.rdb != nil {
+		return s.rdb.SetNX(ctx, fmt.Sprintf("verify:resend:%d", userID), 1, s.verifyResendEvery).Result()
+	}
+	s.resendMu.Lock()
+	defer s.resendMu.Unlock()
+	if last, ok := s.resendAt[userID]; ok && time.Since(last) < s.verifyResendEvery {
+		return false, nil
+	}
+	s.resendAt[userID] = time.Now()
+	return true, nil
+}
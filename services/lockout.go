@@ -0,0 +1,182 @@
+package services // Login brute-force protection (same userService, split out for readability).
+
+import ( // Imports for lockout.
+	"context" // Redis commands.
+	"errors" // Sentinel error.
+	"fmt" // Key/meta formatting.
+	"math" // Retry-After rounding.
+	"strings" // Email normalization.
+	"time" // Windows and lock durations.
+
+	"github.com/redis/go-redis/v9" // Pipelines.
+)
+
+// LockoutConfig controls how failed logins are counted and punished.
+// A zero MaxAccountFailures/MaxIPFailures disables that counter.
+type LockoutConfig struct {
+	MaxAccountFailures int           // Failures per account (email) before a lock.
+	MaxIPFailures      int           // Failures per client IP (any account) before a lock.
+	Window             time.Duration // Failures older than this are forgotten.
+	BaseLockout        time.Duration // First lock duration; doubles with every further lock.
+	MaxLockout         time.Duration // Cap for the doubling.
+}
+
+// DefaultLockoutConfig is used when WithLockout is not supplied.
+func DefaultLockoutConfig() LockoutConfig {
+	return LockoutConfig{MaxAccountFailures: 5, MaxIPFailures: 20, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+}
+
+// WithLockout overrides the brute-force thresholds.
+func WithLockout(cfg LockoutConfig) Option {
+	return func(s *userService) { s.lockout = cfg }
+}
+
+// lockoutMemory is how long we remember past locks when computing the next (doubled) duration.
+const lockoutMemory = 24 * time.Hour
+
+// ErrLoginLocked is matched with errors.Is; the concrete *LockedError carries the wait time.
+var ErrLoginLocked = errors.New("too many failed login attempts")
+
+// LockedError is returned by Login while an account or IP is locked.
+type LockedError struct {
+	RetryAfter time.Duration // How long until the lock expires.
+}
+
+func (e *LockedError) Error() string { return ErrLoginLocked.Error() }
+func (e *LockedError) Unwrap() error { return ErrLoginLocked }
+
+// RetryAfterSeconds rounds up so clients never retry a moment too early.
+func (e *LockedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Key layout:
+//
+//	login:fail:acct:<email>      failure counter, expires after Window
+//	login:fail:ip:<ip>           failure counter, expires after Window
+//	login:lock:acct:<email>      present while locked (TTL = remaining lock)
+//	login:lock:ip:<ip>           present while locked
+//	login:lockcount:acct:<email> number of past locks (drives the doubling)
+//	login:lockcount:ip:<ip>
+func lockoutKey(kind, scope, id string) string { return "login:" + kind + ":" + scope + ":" + id }
+
+// lockoutScope is one counter that applies to a login attempt.
+type lockoutScope struct {
+	scope string // "acct" or "ip"
+	id    string // lower-cased email or client IP
+	max   int    // failures before a lock
+}
+
+// lockoutScopes lists the counters that apply to one attempt.
+func (s *userService) lockoutScopes(email, ip string) []lockoutScope {
+	var out []lockoutScope
+	if s.lockout.MaxAccountFailures > 0 && email != "" {
+		out = append(out, lockoutScope{"acct", strings.ToLower(email), s.lockout.MaxAccountFailures})
+	}
+	if s.lockout.MaxIPFailures > 0 && ip != "" {
+		out = append(out, lockoutScope{"ip", ip, s.lockout.MaxIPFailures})
+	}
+	return out
+}
+
+// loginLock returns the remaining lock for the account or IP (0 when free).
+// Redis errors fail open: an outage must not lock everyone out.
+func (s *userService) loginLock(ctx context.Context, email, ip string) time.Duration {
+	if s.rdb == nil {
+		return 0
+	}
+	var wait time.Duration
+	for _, sc := range s.lockoutScopes(email, ip) {
+		ttl, err := s.rdb.PTTL(ctx, lockoutKey("lock", sc.scope, sc.id)).Result()
+		if err != nil {
+			if s.log != nil { s.log.ErrorContext(ctx, "lockout check error", map[string]string{"err": err.Error()}) }
+			continue
+		}
+		if ttl > wait { // Negative TTL means the key does not exist.
+			wait = ttl
+		}
+	}
+	return wait
+}
+
+// recordLoginFailure bumps the counters and starts a lock when a threshold is reached.
+// It returns the new lock duration (0 if no lock was started).
+func (s *userService) recordLoginFailure(ctx context.Context, email, ip string) time.Duration {
+	if s.rdb == nil {
+		return 0
+	}
+	ctx = context.WithoutCancel(ctx) // Count even if the client hangs up before the answer.
+	var locked time.Duration
+	for _, sc := range s.lockoutScopes(email, ip) {
+		failKey := lockoutKey("fail", sc.scope, sc.id)
+		var n *redis.IntCmd
+		if _, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			n = p.Incr(ctx, failKey)
+			p.ExpireNX(ctx, failKey, s.lockout.Window) // Window starts at the first failure.
+			return nil
+		}); err != nil {
+			if s.log != nil { s.log.ErrorContext(ctx, "lockout count error", map[string]string{"err": err.Error()}) }
+			continue
+		}
+		if n.Val() < int64(sc.max) {
+			continue
+		}
+		d := s.startLock(ctx, sc.scope, sc.id)
+		if d > locked {
+			locked = d
+		}
+	}
+	return locked
+}
+
+// startLock sets the lock key with an exponentially growing duration and resets the counter.
+func (s *userService) startLock(ctx context.Context, scope, id string) time.Duration {
+	countKey := lockoutKey("lockcount", scope, id)
+	prev, _ := s.rdb.Incr(ctx, countKey).Result()
+	_ = s.rdb.Expire(ctx, countKey, lockoutMemory).Err()
+
+	d := s.lockout.BaseLockout
+	for i := int64(1); i < prev && d < s.lockout.MaxLockout; i++ { // base, 2×base, 4×base, ...
+		d *= 2
+	}
+	if s.lockout.MaxLockout > 0 && d > s.lockout.MaxLockout {
+		d = s.lockout.MaxLockout
+	}
+	_ = s.rdb.Set(ctx, lockoutKey("lock", scope, id), 1, d).Err()
+	_ = s.rdb.Del(ctx, lockoutKey("fail", scope, id)).Err() // Fresh count after the lock.
+	key := "email" // Named after what id holds so the redactor masks it.
+	if scope == "ip" {
+		key = "ip"
+	}
+	if s.log != nil { s.log.WarnContext(ctx, "login locked", map[string]string{"scope": scope, key: id, "duration": d.String()}) }
+	return d
+}
+
+// clearLoginFailures forgets the account's failures after a completed login (password, plus the
+// second factor when MFA is on).
+// The IP counter is kept so one valid account cannot be used to reset a spraying attacker.
+func (s *userService) clearLoginFailures(ctx context.Context, email string) {
+	if s.rdb == nil || email == "" {
+		return
+	}
+	id := strings.ToLower(email)
+	_ = s.rdb.Del(ctx, lockoutKey("fail", "acct", id), lockoutKey("lockcount", "acct", id)).Err()
+}
+
+// UnlockUser lifts an account lock and clears its failure history (admin action).
+func (s *userService) UnlockUser(ctx context.Context, id uint) error {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.rdb != nil {
+		key := strings.ToLower(u.Email)
+		if err := s.rdb.Del(ctx,
+			lockoutKey("fail", "acct", key), lockoutKey("lock", "acct", key), lockoutKey("lockcount", "acct", key),
+		).Err(); err != nil {
+			return err
+		}
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "UnlockUser success", map[string]string{"user_id": fmt.Sprint(id)}) }
+	return nil
+}
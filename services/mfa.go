@@ -0,0 +1,265 @@
+package services // TOTP two-factor authentication (same userService, split out for readability).
+
+import ( // Imports for MFA.
+	"context" // Redis replay guard.
+	"crypto/rand" // Recovery code generation.
+	"encoding/hex" // Recovery code formatting.
+	"errors" // Domain errors.
+	"fmt" // Log/meta formatting.
+	"strings" // Code normalization.
+	"time" // TTLs and TOTP time.
+
+	"HelmyTask/models" // User + token models.
+	"HelmyTask/utils" // TOTP + hashing helpers.
+	"HelmyTask/utils/jwtauth" // JWT signer.
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute // How long the second login step may take.
+	totpSkew          = 1 // Accept one 30s step of clock drift either way.
+	recoveryCodeCount = 10 // Codes issued per (re)generation.
+)
+
+// MFA errors.
+var (
+	ErrMFAInvalidCode      = errors.New("invalid authentication code")
+	ErrMFAInvalidChallenge = errors.New("invalid or expired mfa token")
+	ErrMFANotEnrolled      = errors.New("mfa enrollment not started")
+	ErrMFAAlreadyEnabled   = errors.New("mfa already enabled")
+	ErrMFANotEnabled       = errors.New("mfa not enabled")
+)
+
+// WithMFAIssuer sets the issuer label shown in authenticator apps (usually the app name).
+func WithMFAIssuer(issuer string) Option {
+	return func(s *userService) { s.mfaIssuer = issuer }
+}
+
+// EnrollTOTP creates a pending secret; MFA is only enforced after ConfirmTOTP.
+func (s *userService) EnrollTOTP(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	u.TOTPSecret = secret // Pending until confirmed.
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "mfa enroll started", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return &models.TOTPEnrollment{Secret: secret, ProvisioningURI: utils.TOTPProvisioningURI(s.mfaIssuer, u.Email, secret)}, nil
+}
+
+// ConfirmTOTP proves the app is set up, turns MFA on and returns the first recovery codes.
+func (s *userService) ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if !s.checkTOTP(ctx, u, code) {
+		return nil, ErrMFAInvalidCode
+	}
+	codes, err := s.setRecoveryCodes(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	u.MFAEnabled = true
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	s.invalidateUserCache(ctx, u.ID)
+	if s.log != nil { s.log.InfoContext(ctx, "mfa enabled", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return codes, nil
+}
+
+// DisableTOTP turns MFA off; requires the password and a TOTP or recovery code.
+func (s *userService) DisableTOTP(ctx context.Context, userID uint, password, code string) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if !checkPassword(ctx, u.Password, password) {
+		return ErrInvalidCredentials
+	}
+	if ok, err := s.checkSecondFactor(ctx, u, code); err != nil || !ok {
+		return ErrMFAInvalidCode
+	}
+	u.MFAEnabled, u.TOTPSecret, u.RecoveryCodes = false, "", ""
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+	s.invalidateUserCache(ctx, u.ID)
+	if s.log != nil { s.log.InfoContext(ctx, "mfa disabled", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; requires a current TOTP code.
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if !s.checkTOTP(ctx, u, code) {
+		return nil, ErrMFAInvalidCode
+	}
+	codes, err := s.setRecoveryCodes(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "mfa recovery codes regenerated", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return codes, nil
+}
+
+// VerifyMFA completes a two-step login: consumes the challenge and checks the code.
+// The challenge is single-use, so a wrong code means logging in again.
+func (s *userService) VerifyMFA(ctx context.Context, mfaToken, code string, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) {
+	if s.oneTime == nil {
+		return nil, ErrMFAInvalidChallenge
+	}
+	t, err := s.oneTime.Consume(ctx, models.TokenPurposeMFAChallenge, utils.HashToken(mfaToken))
+	if err != nil {
+		return nil, ErrMFAInvalidChallenge
+	}
+	u, err := s.repo.FindByID(ctx, t.UserID)
+	if err != nil || !u.MFAEnabled {
+		return nil, ErrMFAInvalidChallenge
+	}
+	// Wrong codes count against the same account lock as wrong passwords (checked before any bcrypt work).
+	if wait := s.loginLock(ctx, u.Email, ""); wait > 0 {
+		if s.log != nil { s.log.WarnContext(ctx, "mfa while locked", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		return nil, &LockedError{RetryAfter: wait}
+	}
+	ok, err := s.checkSecondFactor(ctx, u, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if s.log != nil { s.log.WarnContext(ctx, "mfa wrong code", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		if d := s.recordLoginFailure(ctx, u.Email, ""); d > 0 {
+			return nil, &LockedError{RetryAfter: d}
+		}
+		return nil, ErrMFAInvalidCode
+	}
+	s.clearLoginFailures(ctx, u.Email) // Both factors passed.
+	family, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.issueTokens(ctx, u, family, signer, exp)
+	if err != nil {
+		return nil, err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "login success", map[string]string{"user_id": fmt.Sprint(u.ID), "mfa": "totp"}) }
+	return resp, nil
+}
+
+// mfaChallenge stores a short-lived challenge and returns it instead of real tokens.
+func (s *userService) mfaChallenge(ctx context.Context, u *models.User) (*models.AuthResponse, error) {
+	if s.oneTime == nil { // Can't do two steps without a token store.
+		return nil, errors.New("mfa unavailable")
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	if err := s.oneTime.Create(ctx, &models.OneTimeToken{
+		Purpose:   models.TokenPurposeMFAChallenge,
+		TokenHash: utils.HashToken(raw),
+		UserID:    u.ID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "login mfa challenge", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return &models.AuthResponse{MFARequired: true, MFAToken: raw, ExpiresAt: expiresAt}, nil
+}
+
+// checkSecondFactor accepts a TOTP code or burns one recovery code.
+func (s *userService) checkSecondFactor(ctx context.Context, u *models.User, code string) (bool, error) {
+	if s.checkTOTP(ctx, u, code) {
+		return true, nil
+	}
+	return s.useRecoveryCode(ctx, u, code)
+}
+
+// checkTOTP validates a code and, with Redis, refuses to accept the same code twice.
+func (s *userService) checkTOTP(ctx context.Context, u *models.User, code string) bool {
+	if u.TOTPSecret == "" || !utils.ValidateTOTP(u.TOTPSecret, code, time.Now(), totpSkew) {
+		return false
+	}
+	if s.rdb != nil { // Replay guard for the validity window.
+		key := fmt.Sprintf("mfa:used:%d:%s", u.ID, strings.TrimSpace(code))
+		ok, err := s.rdb.SetNX(ctx, key, 1, time.Duration(2*totpSkew+1)*30*time.Second).Result()
+		if err == nil && !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// useRecoveryCode removes a matching recovery code from the user and persists the rest.
+func (s *userService) useRecoveryCode(ctx context.Context, u *models.User, code string) (bool, error) {
+	code = normalizeRecoveryCode(code)
+	if u.RecoveryCodes == "" || code == "" {
+		return false, nil
+	}
+	hashes := strings.Split(u.RecoveryCodes, "\n")
+	for i, h := range hashes {
+		if checkPassword(ctx, h, code) {
+			u.RecoveryCodes = strings.Join(append(hashes[:i:i], hashes[i+1:]...), "\n") // Single use.
+			if err := s.repo.Update(ctx, u); err != nil {
+				return false, err
+			}
+			if s.log != nil { s.log.WarnContext(ctx, "mfa recovery code used", map[string]string{"user_id": fmt.Sprint(u.ID), "remaining": fmt.Sprint(len(hashes) - 1)}) }
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setRecoveryCodes generates fresh codes, stores their bcrypt hashes on u and returns the plaintext.
+func (s *userService) setRecoveryCodes(ctx context.Context, u *models.User) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b) // 10 hex chars
+		h, err := hashPassword(ctx, raw)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:]) // Shown as xxxxx-xxxxx.
+		hashes = append(hashes, h)
+	}
+	u.RecoveryCodes = strings.Join(hashes, "\n")
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips the dash/spaces users tend to type.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,30 @@
+package services // Scheduled purge of soft-deleted users.
+
+import ( // Imports for the purge loop.
+	"context" // Stop signal.
+	"log/slog" // Structured logging.
+	"time" // Ticker.
+)
+
+// RunUserPurge calls PurgeDeletedUsers every interval until ctx is cancelled.
+// It runs once right away so a restart does not delay overdue purges.
+// A zero retention or interval disables purging.
+func RunUserPurge(ctx context.Context, svc UserService, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := svc.PurgeDeletedUsers(ctx, retention); err != nil {
+			slog.ErrorContext(ctx, "purge deleted users failed", "err", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "purge removed deleted users", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
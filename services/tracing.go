@@ -0,0 +1,212 @@
+package services // Tracing decorator for UserService (one span per method call) and bcrypt spans.
+
+import ( // Imports for the decorator.
+	"context" // Span parent + cancellation.
+	"time"    // Method signatures.
+
+	"HelmyTask/models"        // Request/response types.
+	"HelmyTask/utils"         // bcrypt helpers.
+	"HelmyTask/utils/jwtauth" // Signer in login methods.
+	"HelmyTask/utils/tracing" // Tracer + End helper.
+
+	"go.opentelemetry.io/otel/attribute" // Span attributes.
+	"go.opentelemetry.io/otel/trace"     // Span type.
+)
+
+// tracedUserService wraps another UserService and records a "UserService.<Method>" span per call.
+type tracedUserService struct {
+	next UserService
+}
+
+// NewTracedUserService decorates next with tracing spans; errors mark the span as failed.
+func NewTracedUserService(next UserService) UserService {
+	return &tracedUserService{next: next}
+}
+
+// start opens the span for one call as a child of the caller's (usually the request's) span.
+func (t *tracedUserService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "UserService."+method, trace.WithAttributes(attrs...))
+}
+
+// hashPassword and checkPassword wrap bcrypt in spans: it is deliberately slow and often
+// the largest slice of a login or register request.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.hash")
+	hash, err := utils.HashPassword(password)
+	tracing.End(span, err)
+	return hash, err
+}
+
+func checkPassword(ctx context.Context, hash, password string) bool {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.compare")
+	defer span.End()
+	return utils.CheckPassword(hash, password)
+}
+
+// userAttr tags a span with the user the call is about.
+func userAttr(id uint) attribute.KeyValue { return attribute.Int64("user.id", int64(id)) }
+
+func (t *tracedUserService) Register(ctx context.Context, req models.RegisterRequest) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "Register")
+	defer func() { tracing.End(span, err) }()
+	return t.next.Register(ctx, req)
+}
+
+func (t *tracedUserService) Login(ctx context.Context, req models.LoginRequest, signer jwtauth.Signer, exp time.Duration) (resp *models.AuthResponse, err error) {
+	ctx, span := t.start(ctx, "Login")
+	defer func() { tracing.End(span, err) }()
+	return t.next.Login(ctx, req, signer, exp)
+}
+
+func (t *tracedUserService) Refresh(ctx context.Context, refreshToken string, signer jwtauth.Signer, exp time.Duration) (resp *models.AuthResponse, err error) {
+	ctx, span := t.start(ctx, "Refresh")
+	defer func() { tracing.End(span, err) }()
+	return t.next.Refresh(ctx, refreshToken, signer, exp)
+}
+
+func (t *tracedUserService) Logout(ctx context.Context, userID uint, jti string, exp time.Time, refreshToken string) (err error) {
+	ctx, span := t.start(ctx, "Logout", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.Logout(ctx, userID, jti, exp, refreshToken)
+}
+
+func (t *tracedUserService) LogoutAll(ctx context.Context, userID uint) (err error) {
+	ctx, span := t.start(ctx, "LogoutAll", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.LogoutAll(ctx, userID)
+}
+
+func (t *tracedUserService) IsTokenRevoked(ctx context.Context, jti string, userID uint, version int64) (revoked bool, err error) {
+	ctx, span := t.start(ctx, "IsTokenRevoked", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.IsTokenRevoked(ctx, jti, userID, version)
+}
+
+func (t *tracedUserService) ForgotPassword(ctx context.Context, email string) (err error) {
+	ctx, span := t.start(ctx, "ForgotPassword")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ForgotPassword(ctx, email)
+}
+
+func (t *tracedUserService) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	ctx, span := t.start(ctx, "ResetPassword")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ResetPassword(ctx, token, newPassword)
+}
+
+func (t *tracedUserService) VerifyEmail(ctx context.Context, token string) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+	return t.next.VerifyEmail(ctx, token)
+}
+
+func (t *tracedUserService) ResendVerification(ctx context.Context, email string) (err error) {
+	ctx, span := t.start(ctx, "ResendVerification")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ResendVerification(ctx, email)
+}
+
+func (t *tracedUserService) EnrollTOTP(ctx context.Context, userID uint) (e *models.TOTPEnrollment, err error) {
+	ctx, span := t.start(ctx, "EnrollTOTP", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.EnrollTOTP(ctx, userID)
+}
+
+func (t *tracedUserService) ConfirmTOTP(ctx context.Context, userID uint, code string) (codes []string, err error) {
+	ctx, span := t.start(ctx, "ConfirmTOTP", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.ConfirmTOTP(ctx, userID, code)
+}
+
+func (t *tracedUserService) DisableTOTP(ctx context.Context, userID uint, password, code string) (err error) {
+	ctx, span := t.start(ctx, "DisableTOTP", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.DisableTOTP(ctx, userID, password, code)
+}
+
+func (t *tracedUserService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) (codes []string, err error) {
+	ctx, span := t.start(ctx, "RegenerateRecoveryCodes", userAttr(userID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.RegenerateRecoveryCodes(ctx, userID, code)
+}
+
+func (t *tracedUserService) VerifyMFA(ctx context.Context, mfaToken, code string, signer jwtauth.Signer, exp time.Duration) (resp *models.AuthResponse, err error) {
+	ctx, span := t.start(ctx, "VerifyMFA")
+	defer func() { tracing.End(span, err) }()
+	return t.next.VerifyMFA(ctx, mfaToken, code, signer, exp)
+}
+
+func (t *tracedUserService) GetByID(ctx context.Context, id uint) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "GetByID", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *tracedUserService) CreateUser(ctx context.Context, req models.RegisterRequest) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "CreateUser")
+	defer func() { tracing.End(span, err) }()
+	return t.next.CreateUser(ctx, req)
+}
+
+func (t *tracedUserService) GetUser(ctx context.Context, id uint) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "GetUser", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.GetUser(ctx, id)
+}
+
+func (t *tracedUserService) UpdateUser(ctx context.Context, id uint, req models.UpdateUserRequest) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "UpdateUser", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.UpdateUser(ctx, id, req)
+}
+
+func (t *tracedUserService) DeleteUser(ctx context.Context, id uint) (err error) {
+	ctx, span := t.start(ctx, "DeleteUser", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.DeleteUser(ctx, id)
+}
+
+func (t *tracedUserService) RestoreUser(ctx context.Context, id uint) (u *models.User, err error) {
+	ctx, span := t.start(ctx, "RestoreUser", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.RestoreUser(ctx, id)
+}
+
+func (t *tracedUserService) ListDeletedUsers(ctx context.Context, page, limit int) (p *models.PagedUsers, err error) {
+	ctx, span := t.start(ctx, "ListDeletedUsers")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ListDeletedUsers(ctx, page, limit)
+}
+
+func (t *tracedUserService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (n int, err error) {
+	ctx, span := t.start(ctx, "PurgeDeletedUsers")
+	defer func() {
+		span.SetAttributes(attribute.Int("users.purged", n))
+		tracing.End(span, err)
+	}()
+	return t.next.PurgeDeletedUsers(ctx, retention)
+}
+
+func (t *tracedUserService) ChangePassword(ctx context.Context, id uint, current, next string) (err error) {
+	ctx, span := t.start(ctx, "ChangePassword", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.ChangePassword(ctx, id, current, next)
+}
+
+func (t *tracedUserService) ListUsers(ctx context.Context, q models.ListUserQuery) (p *models.PagedUsers, err error) {
+	ctx, span := t.start(ctx, "ListUsers", attribute.String("list.paging", q.Paging), attribute.String("list.sort", q.Sort))
+	defer func() { tracing.End(span, err) }()
+	return t.next.ListUsers(ctx, q)
+}
+
+func (t *tracedUserService) UnlockUser(ctx context.Context, id uint) (err error) {
+	ctx, span := t.start(ctx, "UnlockUser", userAttr(id))
+	defer func() { tracing.End(span, err) }()
+	return t.next.UnlockUser(ctx, id)
+}
+
+func (t *tracedUserService) BootstrapAdmin(ctx context.Context, name, email, password string) (err error) {
+	ctx, span := t.start(ctx, "BootstrapAdmin")
+	defer func() { tracing.End(span, err) }()
+	return t.next.BootstrapAdmin(ctx, name, email, password)
+}
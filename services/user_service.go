@@ -5,12 +5,17 @@ import ( // Imports for this service layer.
 	"encoding/json" // For caching user structs as JSON strings in Redis.
 	"errors" // For returning friendly domain errors (e.g., "email already exists").
 	"fmt" // For formatting Redis cache keys.
+	"strings" // Placeholder check in BootstrapAdmin.
+	"sync" // Guards in-process throttle state.
 	"time" // For TTLs and JWT expiration.
 
 	"HelmyTask/core" // Domain helpers; e.g., NormalizeName.
 	"HelmyTask/models" // DTOs and User model.
 	"HelmyTask/repositories" // Repository interface.
 	"HelmyTask/utils" // HashPassword / CheckPassword helpers.
+	"HelmyTask/utils/jwtauth" // JWT signing keys.
+	"HelmyTask/utils/mailer" // Outgoing email abstraction.
+	"HelmyTask/utils/metrics" // Prometheus counters (cache, login).
 	"HelmyTask/utils/redislog" // Redis logger interface (your provided file).
 
 	"github.com/golang-jwt/jwt/v5" // JWT token creation/signing.
@@ -20,16 +25,39 @@ import ( // Imports for this service layer.
 // UserService lists all use-cases that handlers can call.
 type UserService interface {
 	// Auth & read:
-	Register(req models.RegisterRequest) (*models.User, error) // Public register.
-	Login(req models.LoginRequest, jwtSecret string, exp time.Duration) (string, error) // Login and get JWT.
-	GetByID(id uint) (*models.User, error) // Fetch one (cache-aware); used by /me.
+	Register(ctx context.Context, req models.RegisterRequest) (*models.User, error) // Public register.
+	Login(ctx context.Context, req models.LoginRequest, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) // Login and get JWT + refresh token.
+	Refresh(ctx context.Context, refreshToken string, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) // Rotate a refresh token into a new pair.
+	Logout(ctx context.Context, userID uint, jti string, exp time.Time, refreshToken string) error // Revoke the current session.
+	LogoutAll(ctx context.Context, userID uint) error // Revoke every session of the user.
+	IsTokenRevoked(ctx context.Context, jti string, userID uint, version int64) (bool, error) // Used by middlewares.Auth.
+	ForgotPassword(ctx context.Context, email string) error // Email a single-use reset token (silent if the email is unknown).
+	ResetPassword(ctx context.Context, token, newPassword string) error // Consume a reset token and set a new password.
+	VerifyEmail(ctx context.Context, token string) (*models.User, error) // Confirm an email address.
+	ResendVerification(ctx context.Context, email string) error // Re-send the verification email (throttled).
+
+	// MFA:
+	EnrollTOTP(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) // Start TOTP setup (secret + otpauth URI).
+	ConfirmTOTP(ctx context.Context, userID uint, code string) ([]string, error) // Enable TOTP; returns recovery codes.
+	DisableTOTP(ctx context.Context, userID uint, password, code string) error // Turn TOTP off.
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) // Replace recovery codes.
+	VerifyMFA(ctx context.Context, mfaToken, code string, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) // Second login step.
+	GetByID(ctx context.Context, id uint) (*models.User, error) // Fetch one (cache-aware); used by /me.
 
 	// CRUD:
-	CreateUser(req models.RegisterRequest) (*models.User, error) // Admin create (same behavior as register).
-	GetUser(id uint) (*models.User, error) // Read one; alias of GetByID for clarity.
-	UpdateUser(id uint, req models.UpdateUserRequest) (*models.User, error) // Partial update.
-	DeleteUser(id uint) error // Delete by ID.
-	ListUsers(page, limit int) (*models.PagedUsers, error) // Paginated list.
+	CreateUser(ctx context.Context, req models.RegisterRequest) (*models.User, error) // Admin create (same behavior as register).
+	GetUser(ctx context.Context, id uint) (*models.User, error) // Read one; alias of GetByID for clarity.
+	UpdateUser(ctx context.Context, id uint, req models.UpdateUserRequest) (*models.User, error) // Partial update.
+	DeleteUser(ctx context.Context, id uint) error // Soft delete by ID (revokes sessions).
+	RestoreUser(ctx context.Context, id uint) (*models.User, error) // Undo a soft delete.
+	ListDeletedUsers(ctx context.Context, page, limit int) (*models.PagedUsers, error) // Paginated list of soft-deleted users.
+	PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int, error) // Hard-delete users soft-deleted longer than retention.
+	ChangePassword(ctx context.Context, id uint, current, next string) error // Self-service password change (verifies current).
+	ListUsers(ctx context.Context, q models.ListUserQuery) (*models.PagedUsers, error) // Filtered, sorted, paginated list.
+	UnlockUser(ctx context.Context, id uint) error // Lift a login lockout (admin).
+
+	// Setup:
+	BootstrapAdmin(ctx context.Context, name, email, password string) error // Ensure the configured admin account exists.
 }
 
 // userService is the concrete implementation; it depends on repo + Redis + Redis logger.
@@ -37,35 +65,148 @@ type userService struct {
 	repo repositories.UserRepository // Data access abstraction.
 	rdb  *redis.Client // Redis client (may be nil if cache disabled).
 	log  *redislog.Logger // Redis logger (may be nil if not configured).
+
+	refresh    repositories.RefreshTokenRepository // Refresh token store (nil disables refresh tokens).
+	refreshTTL time.Duration // Lifetime of a refresh token.
+
+	revocations repositories.RevocationRepository // Access token deny-list (nil disables server-side logout of JWTs).
+
+	oneTime  repositories.OneTimeTokenRepository // Single-use tokens (password reset).
+	mailer   mailer.Mailer // Outgoing mail (defaults to log output).
+	resetTTL time.Duration // Lifetime of a password reset token.
+
+	requireVerified   bool // Block login until the email is verified.
+	verifyTTL         time.Duration // Lifetime of a verification token.
+	verifyResendEvery time.Duration // Minimum gap between verification emails.
+	resendMu          sync.Mutex // Guards resendAt.
+	resendAt          map[uint]time.Time // Resend throttle when Redis is absent.
+
+	mfaIssuer string // Issuer label in authenticator apps.
+
+	lockout LockoutConfig // Failed-login thresholds (enforced only with Redis).
+
+	cursorSecret []byte // HMAC key for list cursors.
+}
+
+// Option customizes optional dependencies of the service.
+type Option func(*userService)
+
+// WithRefreshTokenRepository sets where refresh tokens are stored (e.g. the DB when Redis is absent).
+func WithRefreshTokenRepository(r repositories.RefreshTokenRepository) Option {
+	return func(s *userService) { s.refresh = r }
+}
+
+// WithRevocationRepository sets where revoked JWT IDs and token versions are kept.
+func WithRevocationRepository(r repositories.RevocationRepository) Option {
+	return func(s *userService) { s.revocations = r }
+}
+
+// WithOneTimeTokenRepository sets where reset tokens are stored (e.g. the DB when Redis is absent).
+func WithOneTimeTokenRepository(r repositories.OneTimeTokenRepository) Option {
+	return func(s *userService) { s.oneTime = r }
+}
+
+// WithMailer sets how emails are delivered.
+func WithMailer(m mailer.Mailer) Option {
+	return func(s *userService) { s.mailer = m }
+}
+
+// WithPasswordResetTTL overrides the reset token lifetime (defaults to defaultResetTTL).
+func WithPasswordResetTTL(ttl time.Duration) Option {
+	return func(s *userService) { s.resetTTL = ttl }
+}
+
+// WithRefreshTTL overrides the refresh token lifetime (defaults to defaultRefreshTTL).
+func WithRefreshTTL(ttl time.Duration) Option {
+	return func(s *userService) { s.refreshTTL = ttl }
+}
+
+// WithCursorSecret sets the key that signs list cursors; instances behind one load balancer
+// must share it. Defaults to a random per-process key.
+func WithCursorSecret(secret []byte) Option {
+	return func(s *userService) { s.cursorSecret = secret }
 }
 
 // NewUserService constructs a service with all dependencies injected.
-func NewUserService(repo repositories.UserRepository, rdb *redis.Client, rlog *redislog.Logger) UserService {
-	return &userService{repo: repo, rdb: rdb, log: rlog} // Return a struct implementing the interface.
+// When no refresh store is given and Redis is available, refresh tokens live in Redis.
+func NewUserService(repo repositories.UserRepository, rdb *redis.Client, rlog *redislog.Logger, opts ...Option) UserService {
+	s := &userService{
+		repo: repo, rdb: rdb, log: rlog,
+		refreshTTL: defaultRefreshTTL, resetTTL: defaultResetTTL, // Token lifetimes.
+		verifyTTL: defaultVerifyTTL, verifyResendEvery: defaultVerifyResendInterval, // Verification settings.
+		resendAt: map[uint]time.Time{},
+		mfaIssuer: "HelmyTask",
+		lockout:   DefaultLockoutConfig(),
+	}
+	for _, opt := range opts { // Apply optional settings.
+		opt(s)
+	}
+	if s.refresh == nil && s.rdb != nil { // Prefer Redis for refresh tokens.
+		s.refresh = repositories.NewRedisRefreshTokenRepository(s.rdb, s.refreshTTL)
+	}
+	if s.revocations == nil && s.rdb != nil { // JWT revocation needs Redis.
+		s.revocations = repositories.NewRedisRevocationRepository(s.rdb)
+	}
+	if s.oneTime == nil && s.rdb != nil { // Reset tokens in Redis by default.
+		s.oneTime = repositories.NewRedisOneTimeTokenRepository(s.rdb)
+	}
+	if len(s.cursorSecret) == 0 { // Cursors then only survive until restart.
+		if raw, err := utils.RandomToken(32); err == nil {
+			s.cursorSecret = []byte(raw)
+		}
+	}
+	if s.mailer == nil { // Never leave the mailer nil.
+		s.mailer = mailer.NewLogMailer(false)
+	}
+	return s // Return a struct implementing the interface.
 }
 
 // userCacheTTL is how long a cached user stays in Redis before expiring.
 const userCacheTTL = 10 * time.Minute // Adjust based on your read/write pattern.
 
+// defaultRefreshTTL is used when WithRefreshTTL is not supplied.
+const defaultRefreshTTL = 30 * 24 * time.Hour
+
+// defaultResetTTL is used when WithPasswordResetTTL is not supplied.
+const defaultResetTTL = 30 * time.Minute
+
+// Refresh errors; handlers map all of them to 401.
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials") // Wrong email or password (deliberately not told apart).
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidResetToken   = errors.New("invalid or expired reset token")
+	ErrInvalidCursor       = repositories.ErrInvalidCursor // Re-exported for handlers (tampered or stale list cursor).
+)
+
 // cacheKeyUser formats a consistent Redis key for a user's cached JSON.
 func (s *userService) cacheKeyUser(id uint) string {
 	return fmt.Sprintf("user:%d", id) // e.g., "user:42".
 }
 
+// invalidateUserCache drops the cached copy of a user (best-effort).
+func (s *userService) invalidateUserCache(ctx context.Context, id uint) {
+	if s.rdb != nil {
+		_ = s.rdb.Del(ctx, s.cacheKeyUser(id)).Err()
+	}
+}
+
 // ---------------- Auth & single read ----------------
 
 // Register creates a new user (after checking email uniqueness), hashes password, and warms cache.
-func (s *userService) Register(req models.RegisterRequest) (*models.User, error) {
-	// Check for existing email to maintain uniqueness.
-	if _, err := s.repo.FindByEmail(req.Email); err == nil { // If no error, a row with that email exists.
-		if s.log != nil { s.log.Warn("register email exists", map[string]string{"email": req.Email}) } // Log to Redis.
+func (s *userService) Register(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	// Check for existing email to maintain uniqueness (soft-deleted accounts still hold theirs).
+	if exists, err := s.repo.EmailExists(ctx, req.Email); err != nil {
+		return nil, err
+	} else if exists {
+		if s.log != nil { s.log.WarnContext(ctx, "register email exists", map[string]string{"email": req.Email}) } // Log to Redis.
 		return nil, errors.New("email already exists") // Return a friendly message for the handler.
 	}
 
 	// Hash the incoming plaintext password before saving.
-	hash, err := utils.HashPassword(req.Password) // Uses bcrypt or similar; defined in utils.
+	hash, err := hashPassword(ctx, req.Password) // Uses bcrypt or similar; defined in utils.
 	if err != nil { // If hashing fails, log and return error.
-		if s.log != nil { s.log.Error("register hash error", map[string]string{"email": req.Email, "err": err.Error()}) }
+		if s.log != nil { s.log.ErrorContext(ctx, "register hash error", map[string]string{"email": req.Email, "err": err.Error()}) }
 		return nil, err
 	}
 
@@ -74,104 +215,365 @@ func (s *userService) Register(req models.RegisterRequest) (*models.User, error)
 		Name:     core.NormalizeName(req.Name), // Apply any naming rules (e.g., capitalize).
 		Email:    req.Email, // Store unique email.
 		Password: hash, // Store hashed password, not plaintext.
+		Role:     models.RoleUser, // Self-registered accounts are never admins.
 	}
 
 	// Insert into the database.
-	if err := s.repo.Create(u); err != nil { // Will set u.ID on success.
-		if s.log != nil { s.log.Error("register db create error", map[string]string{"email": req.Email, "err": err.Error()}) }
+	if err := s.repo.Create(ctx, u); err != nil { // Will set u.ID on success.
+		if s.log != nil { s.log.ErrorContext(ctx, "register db create error", map[string]string{"email": req.Email, "err": err.Error()}) }
 		return nil, err
 	}
 
 	// Optionally warm cache: write the JSON into Redis so the first /me is a HIT.
 	if s.rdb != nil { // Only if Redis is configured.
-		ctx := context.Background() // Use a background context for one-off calls.
 		if b, _ := json.Marshal(u); len(b) > 0 { // Marshal struct -> JSON bytes.
 			_ = s.rdb.Set(ctx, s.cacheKeyUser(u.ID), b, userCacheTTL).Err() // SET key value EX ttl
-			if s.log != nil { s.log.Info("cache warm after register", map[string]string{"key": s.cacheKeyUser(u.ID), "user_id": fmt.Sprint(u.ID)}) }
+			if s.log != nil { s.log.InfoContext(ctx, "cache warm after register", map[string]string{"key": s.cacheKeyUser(u.ID), "user_id": fmt.Sprint(u.ID)}) }
 		}
 	}
 
+	// Send the verification email; registration itself still succeeds if mail fails (user can resend).
+	// It takes the resend slot, so an immediate resend does not mail a second token.
+	_, _ = s.allowResend(ctx, u.ID)
+	if err := s.sendVerification(ctx, u); err != nil && s.log != nil {
+		s.log.ErrorContext(ctx, "register verification mail error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()})
+	}
+
 	// Log final success of the registration flow.
-	if s.log != nil { s.log.Info("register success", map[string]string{"user_id": fmt.Sprint(u.ID), "email": u.Email}) }
+	if s.log != nil { s.log.InfoContext(ctx, "register success", map[string]string{"user_id": fmt.Sprint(u.ID), "email": u.Email}) }
 	return u, nil // Return created user (password omitted in JSON due to json:"-").
 }
 
-// Login validates credentials and issues a signed JWT.
-func (s *userService) Login(req models.LoginRequest, jwtSecret string, exp time.Duration) (string, error) {
+// Login validates credentials and issues a signed JWT plus a refresh token (new family).
+// Repeated failures lock the account and/or client IP; locked attempts return *LockedError.
+func (s *userService) Login(ctx context.Context, req models.LoginRequest, signer jwtauth.Signer, exp time.Duration) (resp *models.AuthResponse, err error) {
+	defer func() { metrics.LoginResult(loginOutcome(resp, err)) }() // Count every exit path.
+
+	// Refuse early while locked, before spending a bcrypt comparison.
+	if wait := s.loginLock(ctx, req.Email, req.ClientIP); wait > 0 {
+		if s.log != nil { s.log.WarnContext(ctx, "login while locked", map[string]string{"email": req.Email, "ip": req.ClientIP}) }
+		return nil, &LockedError{RetryAfter: wait}
+	}
 	// Look up by email; return invalid on any error (don't leak info).
-	u, err := s.repo.FindByEmail(req.Email)
+	u, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil { // If not found or DB error, treat as invalid.
-		if s.log != nil { s.log.Warn("login user not found", map[string]string{"email": req.Email}) }
-		return "", errors.New("invalid credentials")
+		if ctx.Err() != nil { // Timed out or cancelled: not a failed attempt.
+			return nil, ctx.Err()
+		}
+		if s.log != nil { s.log.WarnContext(ctx, "login user not found", map[string]string{"email": req.Email}) }
+		return nil, s.loginFailed(ctx, req) // Unknown emails count too, so locks do not reveal which accounts exist.
 	}
 	// Verify supplied password against stored bcrypt hash.
-	if !utils.CheckPassword(u.Password, req.Password) {
-		if s.log != nil { s.log.Warn("login wrong password", map[string]string{"email": req.Email}) }
-		return "", errors.New("invalid credentials")
+	if !checkPassword(ctx, u.Password, req.Password) {
+		if s.log != nil { s.log.WarnContext(ctx, "login wrong password", map[string]string{"email": req.Email}) }
+		return nil, s.loginFailed(ctx, req)
+	}
+	// Optionally require a confirmed email (checked after the password so it leaks nothing).
+	if s.requireVerified && u.EmailVerifiedAt == nil {
+		if s.log != nil { s.log.WarnContext(ctx, "login email not verified", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		return nil, ErrEmailNotVerified
+	}
+
+	// With 2FA on, hand out a challenge instead of tokens. Failures are only cleared once
+	// VerifyMFA succeeds, otherwise every correct password would reset the code guessing.
+	if u.MFAEnabled {
+		return s.mfaChallenge(ctx, u)
+	}
+	s.clearLoginFailures(ctx, req.Email) // Login complete → start counting from zero again.
+
+	// Every login starts a new refresh token family.
+	family, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = s.issueTokens(ctx, u, family, signer, exp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log login success (helpful audit trail).
+	if s.log != nil { s.log.InfoContext(ctx, "login success", map[string]string{"user_id": fmt.Sprint(u.ID), "email": u.Email}) }
+	return resp, nil // Return access JWT + refresh token.
+}
+
+// loginOutcome labels a Login result for auth_login_attempts_total.
+func loginOutcome(resp *models.AuthResponse, err error) string {
+	switch {
+	case err == nil && resp != nil && resp.MFARequired:
+		return "mfa_required"
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrLoginLocked):
+		return "locked"
+	case errors.Is(err, ErrEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, ErrInvalidCredentials):
+		return "invalid_credentials"
+	default:
+		return "error"
+	}
+}
+
+// loginFailed records a failed attempt and returns the error to hand back to the caller.
+func (s *userService) loginFailed(ctx context.Context, req models.LoginRequest) error {
+	if d := s.recordLoginFailure(ctx, req.Email, req.ClientIP); d > 0 {
+		return &LockedError{RetryAfter: d}
+	}
+	return ErrInvalidCredentials
+}
+
+// Refresh exchanges a refresh token for a new access/refresh pair (rotation).
+// Presenting an already-rotated token is treated as theft and revokes the whole family.
+func (s *userService) Refresh(ctx context.Context, refreshToken string, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) {
+	if s.refresh == nil { // Refresh tokens are not configured.
+		return nil, ErrInvalidRefreshToken
+	}
+	hash := utils.HashToken(refreshToken) // We only store digests.
+	rt, err := s.refresh.FindByHash(ctx, hash)
+	if err != nil { // Unknown, expired (Redis TTL) or DB error.
+		if s.log != nil && !repositories.IsNotFound(err) { s.log.ErrorContext(ctx, "refresh lookup error", map[string]string{"err": err.Error()}) }
+		return nil, ErrInvalidRefreshToken
+	}
+	if rt.RevokedAt != nil || time.Now().After(rt.ExpiresAt) { // Revoked family or expired row.
+		if s.log != nil { s.log.WarnContext(ctx, "refresh token rejected", map[string]string{"user_id": fmt.Sprint(rt.UserID), "family": rt.FamilyID}) }
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Claim the token; only one caller may rotate it.
+	ok, err := s.refresh.MarkUsed(ctx, hash)
+	if err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "refresh mark used error", map[string]string{"err": err.Error()}) }
+		return nil, err
+	}
+	if !ok { // Already used → someone replayed it; kill the family.
+		if err := s.refresh.RevokeFamily(ctx, rt.FamilyID); err != nil && s.log != nil {
+			s.log.ErrorContext(ctx, "refresh revoke family error", map[string]string{"family": rt.FamilyID, "err": err.Error()})
+		}
+		if s.log != nil { s.log.WarnContext(ctx, "refresh token reuse detected", map[string]string{"user_id": fmt.Sprint(rt.UserID), "family": rt.FamilyID}) }
+		return nil, ErrRefreshTokenReused
+	}
+
+	// Load the user again so deleted accounts cannot keep refreshing.
+	u, err := s.repo.FindByID(ctx, rt.UserID)
+	if err != nil {
+		_ = s.refresh.RevokeFamily(ctx, rt.FamilyID) // Best-effort cleanup.
+		return nil, ErrInvalidRefreshToken
+	}
+	resp, err := s.issueTokens(ctx, u, rt.FamilyID, signer, exp) // Same family, new token.
+	if err != nil {
+		return nil, err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "refresh success", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return resp, nil
+}
+
+// Logout revokes the presented access token (by jti) and, if given, the refresh token family.
+func (s *userService) Logout(ctx context.Context, userID uint, jti string, exp time.Time, refreshToken string) error {
+	if s.revocations != nil && jti != "" { // Deny-list the jti until it would expire anyway.
+		if err := s.revocations.RevokeJTI(ctx, jti, time.Until(exp)); err != nil {
+			if s.log != nil { s.log.ErrorContext(ctx, "logout revoke jti error", map[string]string{"user_id": fmt.Sprint(userID), "err": err.Error()}) }
+			return err
+		}
+	}
+	if refreshToken != "" && s.refresh != nil { // Kill the refresh chain of this session too.
+		rt, err := s.refresh.FindByHash(ctx, utils.HashToken(refreshToken))
+		if err == nil && rt.UserID == userID { // Never let a user revoke someone else's family.
+			if err := s.refresh.RevokeFamily(ctx, rt.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "logout success", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return nil
+}
+
+// LogoutAll bumps the user's token version (invalidating every JWT) and revokes all refresh tokens.
+func (s *userService) LogoutAll(ctx context.Context, userID uint) error {
+	if s.revocations != nil {
+		if _, err := s.revocations.BumpVersion(ctx, userID); err != nil {
+			if s.log != nil { s.log.ErrorContext(ctx, "logout all bump version error", map[string]string{"user_id": fmt.Sprint(userID), "err": err.Error()}) }
+			return err
+		}
+	}
+	if s.refresh != nil {
+		if err := s.refresh.RevokeUser(ctx, userID); err != nil {
+			if s.log != nil { s.log.ErrorContext(ctx, "logout all revoke refresh error", map[string]string{"user_id": fmt.Sprint(userID), "err": err.Error()}) }
+			return err
+		}
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "logout all success", map[string]string{"user_id": fmt.Sprint(userID)}) }
+	return nil
+}
+
+// IsTokenRevoked reports whether a JWT was logged out; always false when no revocation store exists.
+func (s *userService) IsTokenRevoked(ctx context.Context, jti string, userID uint, version int64) (bool, error) {
+	if s.revocations == nil {
+		return false, nil
+	}
+	return s.revocations.IsRevoked(ctx, jti, userID, version)
+}
+
+// ForgotPassword mails a single-use reset token. It returns nil for unknown emails
+// so the endpoint cannot be used to discover accounts.
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	if s.oneTime == nil { // No token store configured.
+		return errors.New("password reset unavailable")
+	}
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err != nil { // Unknown email → pretend success.
+		if s.log != nil { s.log.InfoContext(ctx, "forgot password unknown email", map[string]string{"email": email}) }
+		return nil
+	}
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.oneTime.Create(ctx, &models.OneTimeToken{
+		Purpose:   models.TokenPurposePasswordReset,
+		TokenHash: utils.HashToken(raw), // Only the digest is stored.
+		UserID:    u.ID,
+		ExpiresAt: time.Now().Add(s.resetTTL),
+	}); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "forgot password store error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()}) }
+		return err
+	}
+	body := fmt.Sprintf("Use this token to reset your password (valid for %s):\n\n%s\n\nIf you did not ask for this, ignore this email.", s.resetTTL, raw)
+	if err := s.mailer.Send(u.Email, "Reset your password", body); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "forgot password mail error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()}) }
+		return err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "forgot password token sent", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return nil
+}
+
+// ResetPassword consumes a reset token, stores the new password and revokes all sessions.
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if s.oneTime == nil {
+		return ErrInvalidResetToken
+	}
+	t, err := s.oneTime.Consume(ctx, models.TokenPurposePasswordReset, utils.HashToken(token))
+	if err != nil { // Unknown, used or expired.
+		if s.log != nil && !repositories.IsNotFound(err) { s.log.ErrorContext(ctx, "reset password consume error", map[string]string{"err": err.Error()}) }
+		return ErrInvalidResetToken
+	}
+	u, err := s.repo.FindByID(ctx, t.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	hash, err := hashPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	if err := s.repo.Update(ctx, u); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "reset password db error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()}) }
+		return err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "reset password success", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return s.LogoutAll(ctx, u.ID) // Whoever had the old password loses their sessions.
+}
+
+// issueTokens signs an access JWT and, if a refresh store exists, mints a refresh token in the given family.
+func (s *userService) issueTokens(ctx context.Context, u *models.User, family string, signer jwtauth.Signer, exp time.Duration) (*models.AuthResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(exp)
+
+	// Unique token ID so this session can be revoked on its own.
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	// Current token version; LogoutAll bumps it to invalidate older tokens.
+	var ver int64
+	if s.revocations != nil {
+		if ver, err = s.revocations.Version(ctx, u.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Build JWT claims (subject, issued-at, expiration, plus optional email).
 	claims := jwt.MapClaims{
 		"sub": u.ID, // Subject: user ID.
-		"exp": time.Now().Add(exp).Unix(), // Expiration time (unix seconds).
-		"iat": time.Now().Unix(), // Issued-at (unix seconds).
+		"exp": expiresAt.Unix(), // Expiration time (unix seconds).
+		"iat": now.Unix(), // Issued-at (unix seconds).
+		"jti": jti, // Token ID for per-session revocation.
+		"ver": ver, // Token version for "logout everywhere".
+		"role": u.Role, // Role for middlewares.RequireRole.
 		"eml": u.Email, // Optional claim to carry email.
 	}
-	// Create a token with HS256 signing method.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Sign the token with the shared secret.
-	signed, err := token.SignedString([]byte(jwtSecret))
+	// Sign with the active key (HS256 secret or RS256/ES256/EdDSA private key; sets the kid header).
+	signed, err := signer.Sign(claims)
 	if err != nil { // Log and propagate signing error.
-		if s.log != nil { s.log.Error("login token sign error", map[string]string{"email": u.Email, "err": err.Error()}) }
-		return "", err
+		if s.log != nil { s.log.ErrorContext(ctx, "login token sign error", map[string]string{"email": u.Email, "err": err.Error()}) }
+		return nil, err
 	}
+	resp := &models.AuthResponse{Token: signed, ExpiresAt: expiresAt}
 
-	// Log login success (helpful audit trail).
-	if s.log != nil { s.log.Info("login success", map[string]string{"user_id": fmt.Sprint(u.ID), "email": u.Email}) }
-	return signed, nil // Return compact JWT string.
+	if s.refresh == nil { // No store → access token only.
+		return resp, nil
+	}
+	raw, err := utils.RandomToken(32) // Opaque, high-entropy value.
+	if err != nil {
+		return nil, err
+	}
+	refreshExp := now.Add(s.refreshTTL)
+	if err := s.refresh.Create(ctx, &models.RefreshToken{
+		TokenHash: utils.HashToken(raw),
+		FamilyID:  family,
+		UserID:    u.ID,
+		ExpiresAt: refreshExp,
+	}); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "refresh token store error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()}) }
+		return nil, err
+	}
+	resp.RefreshToken = raw
+	resp.RefreshExpiresAt = &refreshExp
+	return resp, nil
 }
 
 // GetByID returns a user, preferring Redis cache and falling back to DB.
-func (s *userService) GetByID(id uint) (*models.User, error) {
+func (s *userService) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	// Try Redis first for speed.
 	if s.rdb != nil { // Only if Redis configured.
-		ctx := context.Background() // Context needed for Redis commands.
 		key := s.cacheKeyUser(id) // Compose key like "user:1".
-		if s.log != nil { s.log.Info("cache try GET", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
+		if s.log != nil { s.log.InfoContext(ctx, "cache try GET", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
 
 		val, err := s.rdb.Get(ctx, key).Result() // Attempt GET.
 		if err == nil { // Found a value (string).
 			var u models.User // Destination struct.
 			if json.Unmarshal([]byte(val), &u) == nil { // Decode JSON → struct.
-				if s.log != nil { s.log.Info("cache HIT", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
+				metrics.CacheResult("user", metrics.CacheHit)
+				if s.log != nil { s.log.InfoContext(ctx, "cache HIT", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
 				return &u, nil // Return cached result immediately.
 			}
 			// If unmarshal failed, ignore cache and continue to DB.
-			if s.log != nil { s.log.Warn("cache unmarshal failed", map[string]string{"key": key}) }
+			metrics.CacheResult("user", metrics.CacheError)
+			if s.log != nil { s.log.WarnContext(ctx, "cache unmarshal failed", map[string]string{"key": key}) }
 		} else if err == redis.Nil { // Key not present → MISS.
-			if s.log != nil { s.log.Warn("cache MISS", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
+			metrics.CacheResult("user", metrics.CacheMiss)
+			if s.log != nil { s.log.WarnContext(ctx, "cache MISS", map[string]string{"key": key, "user_id": fmt.Sprint(id)}) }
 		} else { // Some other Redis error occurred.
-			if s.log != nil { s.log.Error("cache GET error", map[string]string{"key": key, "err": err.Error()}) }
+			metrics.CacheResult("user", metrics.CacheError)
+			if s.log != nil { s.log.ErrorContext(ctx, "cache GET error", map[string]string{"key": key, "err": err.Error()}) }
 		}
 	}
 
 	// Fallback to DB if cache did not return a valid user.
-	u, err := s.repo.FindByID(id) // Query DB.
+	u, err := s.repo.FindByID(ctx, id) // Query DB.
 	if err != nil { // Not found or DB error → propagate.
-		if s.log != nil { s.log.Error("db fetch error in GetByID", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+		if s.log != nil { s.log.ErrorContext(ctx, "db fetch error in GetByID", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
 		return nil, err
 	}
-	if s.log != nil { s.log.Info("db fetch success in GetByID", map[string]string{"user_id": fmt.Sprint(id)}) }
+	if s.log != nil { s.log.InfoContext(ctx, "db fetch success in GetByID", map[string]string{"user_id": fmt.Sprint(id)}) }
 
 	// Store result in cache for next time.
 	if s.rdb != nil { // Only if Redis configured.
-		ctx := context.Background() // Redis context.
 		key := s.cacheKeyUser(id) // Cache key again.
 		if b, _ := json.Marshal(u); len(b) > 0 { // Marshal user to JSON.
 			if err := s.rdb.Set(ctx, key, b, userCacheTTL).Err(); err == nil { // SET key value with TTL.
-				if s.log != nil { s.log.Info("cache SET", map[string]string{"key": key, "user_id": fmt.Sprint(id), "ttl": userCacheTTL.String()}) }
+				if s.log != nil { s.log.InfoContext(ctx, "cache SET", map[string]string{"key": key, "user_id": fmt.Sprint(id), "ttl": userCacheTTL.String()}) }
 			} else { // Log cache SET failure if it happens.
-				if s.log != nil { s.log.Error("cache SET error", map[string]string{"key": key, "err": err.Error()}) }
+				if s.log != nil { s.log.ErrorContext(ctx, "cache SET error", map[string]string{"key": key, "err": err.Error()}) }
 			}
 		}
 	}
@@ -181,116 +583,344 @@ func (s *userService) GetByID(id uint) (*models.User, error) {
 // ---------------- CRUD ----------------
 
 // CreateUser — admin-style create; use same semantics as Register.
-func (s *userService) CreateUser(req models.RegisterRequest) (*models.User, error) {
-	if s.log != nil { s.log.Info("CreateUser called", map[string]string{"email": req.Email}) } // Trace call.
-	return s.Register(req) // Reuse register path for uniqueness & hashing logic.
+func (s *userService) CreateUser(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	if s.log != nil { s.log.InfoContext(ctx, "CreateUser called", map[string]string{"email": req.Email}) } // Trace call.
+	return s.Register(ctx, req) // Reuse register path for uniqueness & hashing logic.
 }
 
 // GetUser — explicit method name for CRUD; same as GetByID.
-func (s *userService) GetUser(id uint) (*models.User, error) {
-	if s.log != nil { s.log.Info("GetUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
-	return s.GetByID(id) // Reuse existing cache-aware read.
+func (s *userService) GetUser(ctx context.Context, id uint) (*models.User, error) {
+	if s.log != nil { s.log.InfoContext(ctx, "GetUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
+	return s.GetByID(ctx, id) // Reuse existing cache-aware read.
 }
 
 // UpdateUser applies partial updates; re-hashes password if provided; refreshes cache.
-func (s *userService) UpdateUser(id uint, req models.UpdateUserRequest) (*models.User, error) {
-	if s.log != nil { s.log.Info("UpdateUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
+func (s *userService) UpdateUser(ctx context.Context, id uint, req models.UpdateUserRequest) (*models.User, error) {
+	if s.log != nil { s.log.InfoContext(ctx, "UpdateUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
 
 	// Load current user state.
-	u, err := s.repo.FindByID(id)
+	u, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		if s.log != nil { s.log.Error("UpdateUser not found", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+		if s.log != nil { s.log.ErrorContext(ctx, "UpdateUser not found", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
 		return nil, err
 	}
 
 	// Apply provided changes.
+	emailChanged := false
 	if req.Name != nil { // Update name if provided.
 		u.Name = core.NormalizeName(*req.Name) // Normalize new name.
 	}
 	if req.Email != nil { // If email change requested...
 		if *req.Email != u.Email { // Only if it's different.
-			if _, err := s.repo.FindByEmail(*req.Email); err == nil { // Check uniqueness.
-				if s.log != nil { s.log.Warn("UpdateUser email exists", map[string]string{"email": *req.Email}) }
+			if exists, err := s.repo.EmailExists(ctx, *req.Email); err != nil {
+				return nil, err
+			} else if exists { // Check uniqueness (deleted accounts included).
+				if s.log != nil { s.log.WarnContext(ctx, "UpdateUser email exists", map[string]string{"email": *req.Email}) }
 				return nil, errors.New("email already exists") // Abort on conflict.
 			}
 			u.Email = *req.Email // Apply new email.
+			u.EmailVerifiedAt = nil // New address must be verified again.
+			emailChanged = true
 		}
 	}
 	if req.Password != nil { // If new password provided...
-		hash, err := utils.HashPassword(*req.Password) // Hash it.
+		hash, err := hashPassword(ctx, *req.Password) // Hash it.
 		if err != nil {
-			if s.log != nil { s.log.Error("UpdateUser hash error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+			if s.log != nil { s.log.ErrorContext(ctx, "UpdateUser hash error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
 			return nil, err
 		}
 		u.Password = hash // Store hashed password.
 	}
+	passwordChanged := req.Password != nil
+	roleChanged := false
+	if req.Role != nil && *req.Role != u.Role { // Role change (handler only allows admins to send it).
+		u.Role = *req.Role
+		roleChanged = true
+	}
 
 	// Persist the update.
-	if err := s.repo.Update(u); err != nil { // Write to DB.
-		if s.log != nil { s.log.Error("UpdateUser db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+	if err := s.repo.Update(ctx, u); err != nil { // Write to DB.
+		if s.log != nil { s.log.ErrorContext(ctx, "UpdateUser db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
 		return nil, err
 	}
 
 	// Refresh cache: delete the old value and set new.
 	if s.rdb != nil {
-		ctx := context.Background() // Redis context.
 		key := s.cacheKeyUser(id) // Cache key.
 		_ = s.rdb.Del(ctx, key).Err() // Best-effort invalidate; ignore error.
 		if b, _ := json.Marshal(u); len(b) > 0 { // Marshal updated user.
 			_ = s.rdb.Set(ctx, key, b, userCacheTTL).Err() // Best-effort set; ignore error.
 		}
-		if s.log != nil { s.log.Info("UpdateUser cache refreshed", map[string]string{"key": key}) } // Log cache refresh.
+		if s.log != nil { s.log.InfoContext(ctx, "UpdateUser cache refreshed", map[string]string{"key": key}) } // Log cache refresh.
+	}
+
+	// A changed address gets a fresh verification email (best-effort).
+	if emailChanged {
+		if err := s.sendVerification(ctx, u); err != nil && s.log != nil {
+			s.log.ErrorContext(ctx, "UpdateUser verification mail error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()})
+		}
+	}
+
+	// Like ChangePassword: sessions opened with the old password must not survive a reset.
+	if passwordChanged {
+		if s.log != nil { s.log.InfoContext(ctx, "UpdateUser password set", map[string]string{"user_id": fmt.Sprint(id)}) }
+		if err := s.LogoutAll(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tokens carry the role claim; force a fresh login so the new role takes effect.
+	if roleChanged && !passwordChanged && s.revocations != nil { // LogoutAll already bumped the version.
+		_, _ = s.revocations.BumpVersion(ctx, u.ID) // Best-effort.
+		if s.log != nil { s.log.InfoContext(ctx, "UpdateUser role changed", map[string]string{"user_id": fmt.Sprint(id), "role": u.Role}) }
 	}
 
 	// Return updated user.
 	return u, nil
 }
 
-// DeleteUser removes a user and deletes any cache entry.
-func (s *userService) DeleteUser(id uint) error {
-	if s.log != nil { s.log.Info("DeleteUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
+// ChangePassword verifies the current password, stores the new hash and revokes every session,
+// so a stolen token does not survive the change.
+func (s *userService) ChangePassword(ctx context.Context, id uint, current, next string) error {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !checkPassword(ctx, u.Password, current) { // Wrong current password.
+		if s.log != nil { s.log.WarnContext(ctx, "ChangePassword wrong password", map[string]string{"user_id": fmt.Sprint(id)}) }
+		return errors.New("invalid current password")
+	}
+	hash, err := hashPassword(ctx, next)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	if err := s.repo.Update(ctx, u); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "ChangePassword db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+		return err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "ChangePassword success", map[string]string{"user_id": fmt.Sprint(id)}) }
+	return s.LogoutAll(ctx, id) // Old tokens must not outlive the old password.
+}
+
+// DeleteUser soft-deletes a user, deletes any cache entry and revokes the user's sessions.
+// The row can be restored until PurgeDeletedUsers removes it.
+func (s *userService) DeleteUser(ctx context.Context, id uint) error {
+	if s.log != nil { s.log.InfoContext(ctx, "DeleteUser called", map[string]string{"user_id": fmt.Sprint(id)}) } // Trace call.
 
-	// Delete from DB (returns ErrRecordNotFound if not present).
-	if err := s.repo.Delete(id); err != nil {
-		if s.log != nil { s.log.Error("DeleteUser db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+	// Soft delete in DB (returns ErrRecordNotFound if not present).
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "DeleteUser db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
 		return err
 	}
 
 	// Delete cache key to avoid stale reads.
 	if s.rdb != nil {
-		ctx := context.Background() // Redis context.
 		_ = s.rdb.Del(ctx, s.cacheKeyUser(id)).Err() // Best-effort delete.
 	}
 
+	// A deleted account must not keep working through tokens issued earlier.
+	_ = s.LogoutAll(ctx, id) // Best-effort; errors are logged inside.
+
 	// Log success.
-	if s.log != nil { s.log.Info("DeleteUser success", map[string]string{"user_id": fmt.Sprint(id)}) }
+	if s.log != nil { s.log.InfoContext(ctx, "DeleteUser success", map[string]string{"user_id": fmt.Sprint(id)}) }
 	return nil // Done.
 }
 
-// ListUsers returns a paginated page of users and total count.
-func (s *userService) ListUsers(page, limit int) (*models.PagedUsers, error) {
-	if s.log != nil { s.log.Info("ListUsers called", map[string]string{"page": fmt.Sprint(page), "limit": fmt.Sprint(limit)}) } // Trace.
+// ListUsers returns a filtered, sorted page of users and the total number of matches.
+func (s *userService) ListUsers(ctx context.Context, q models.ListUserQuery) (*models.PagedUsers, error) {
+	page, limit := q.Page, q.Limit
+	if s.log != nil { s.log.InfoContext(ctx, "ListUsers called", map[string]string{"page": fmt.Sprint(page), "limit": fmt.Sprint(limit), "sort": q.Sort}) } // Trace.
 
 	// Sanitize inputs: default page=1, limit=10..100
 	if page < 1 { page = 1 } // Avoid zero/negative page.
 	if limit <= 0 || limit > 100 { limit = 10 } // Clamp page size.
 
+	// Keyset mode: no OFFSET, optional COUNT.
+	if q.Cursor != "" || q.Paging == models.PagingCursor {
+		return s.listUsersByCursor(ctx, q, limit)
+	}
+
 	// Compute offset for SQL LIMIT/OFFSET.
 	offset := (page - 1) * limit // Skip previous pages.
 
 	// Query repository for items + total.
-	items, total, err := s.repo.List(offset, limit)
+	items, total, err := s.repo.List(ctx, q, repositories.Page{Offset: offset, Limit: limit, Count: true})
 	if err != nil { // Propagate DB error to handler.
-		if s.log != nil { s.log.Error("ListUsers db error", map[string]string{"err": err.Error()}) }
+		if s.log != nil { s.log.ErrorContext(ctx, "ListUsers db error", map[string]string{"err": err.Error()}) }
 		return nil, err
 	}
 
 	// Compose response envelope with items & paging info.
-	resp := &models.PagedUsers{Items: items, Total: total, Page: page, Limit: limit}
+	resp := &models.PagedUsers{Items: items, Total: &total, Page: page, Limit: limit}
 
 	// Optional log of result size (useful for monitoring).
-	if s.log != nil { s.log.Info("ListUsers success", map[string]string{"count": fmt.Sprint(len(items)), "total": fmt.Sprint(total)}) }
+	if s.log != nil { s.log.InfoContext(ctx, "ListUsers success", map[string]string{"count": fmt.Sprint(len(items)), "total": fmt.Sprint(total)}) }
 
 	// Return page.
 	return resp, nil
 }
+
+// listUsersByCursor serves one keyset page. It fetches limit+1 rows to learn whether
+// another page exists in the direction of travel.
+func (s *userService) listUsersByCursor(ctx context.Context, q models.ListUserQuery, limit int) (*models.PagedUsers, error) {
+	var after *models.UserCursor
+	if q.Cursor != "" {
+		var c models.UserCursor
+		if err := utils.OpenCursor(s.cursorSecret, q.Cursor, &c); err != nil || c.Sort != q.Sort { // Forged, or sort changed.
+			return nil, ErrInvalidCursor
+		}
+		after = &c
+	}
+	items, total, err := s.repo.List(ctx, q, repositories.Page{Limit: limit + 1, After: after, Count: q.WithTotal})
+	if err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "ListUsers db error", map[string]string{"err": err.Error()}) }
+		return nil, err
+	}
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	backward := after != nil && after.Backward
+	if backward { // Repo returned nearest-first; restore the requested order.
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	resp := &models.PagedUsers{Items: items, Limit: limit}
+	if q.WithTotal {
+		resp.Total = &total
+	}
+	if len(items) > 0 {
+		if more || backward { // Rows exist after the last item.
+			if resp.NextCursor, err = s.userCursor(&items[len(items)-1], q.Sort, false); err != nil {
+				return nil, err
+			}
+		}
+		if (backward && more) || (!backward && after != nil) { // Rows exist before the first item.
+			if resp.PrevCursor, err = s.userCursor(&items[0], q.Sort, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "ListUsers success", map[string]string{"count": fmt.Sprint(len(items)), "mode": models.PagingCursor}) }
+	return resp, nil
+}
+
+// userCursor signs the boundary of a page.
+func (s *userService) userCursor(u *models.User, sort string, backward bool) (string, error) {
+	c, err := repositories.NewUserCursor(u, sort, backward)
+	if err != nil {
+		return "", err
+	}
+	return utils.SignCursor(s.cursorSecret, c)
+}
+
+// RestoreUser clears deleted_at so the account works again (old sessions stay revoked).
+func (s *userService) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	u, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		if s.log != nil && !repositories.IsNotFound(err) { s.log.ErrorContext(ctx, "RestoreUser db error", map[string]string{"user_id": fmt.Sprint(id), "err": err.Error()}) }
+		return nil, err
+	}
+	s.invalidateUserCache(ctx, id) // Next read goes to the DB.
+	if s.log != nil { s.log.InfoContext(ctx, "RestoreUser success", map[string]string{"user_id": fmt.Sprint(id)}) }
+	return u, nil
+}
+
+// ListDeletedUsers pages through soft-deleted users (admin view).
+func (s *userService) ListDeletedUsers(ctx context.Context, page, limit int) (*models.PagedUsers, error) {
+	if page < 1 { page = 1 }
+	if limit <= 0 || limit > 100 { limit = 10 }
+	items, total, err := s.repo.ListDeleted(ctx, (page-1)*limit, limit)
+	if err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "ListDeletedUsers db error", map[string]string{"err": err.Error()}) }
+		return nil, err
+	}
+	return &models.PagedUsers{Items: items, Total: &total, Page: page, Limit: limit}, nil
+}
+
+// PurgeDeletedUsers permanently removes users that were soft-deleted more than retention ago.
+func (s *userService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int, error) {
+	users, err := s.repo.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		if s.log != nil { s.log.ErrorContext(ctx, "PurgeDeletedUsers db error", map[string]string{"err": err.Error()}) }
+		return 0, err
+	}
+	for _, u := range users {
+		s.invalidateUserCache(ctx, u.ID) // Cache was dropped on delete already; this guards against late writes.
+		if err := s.forgetUser(ctx, u); err != nil { // Row is gone either way; leftovers expire or are harmless.
+			if s.log != nil { s.log.WarnContext(ctx, "PurgeDeletedUsers redis cleanup error", map[string]string{"user_id": fmt.Sprint(u.ID), "err": err.Error()}) }
+		}
+	}
+	if len(users) > 0 && s.log != nil { s.log.InfoContext(ctx, "PurgeDeletedUsers success", map[string]string{"count": fmt.Sprint(len(users))}) }
+	return len(users), nil
+}
+
+// forgetUser drops the Redis state a purged account leaves behind: account lockout counters,
+// the verification resend throttle and (once older tokens have expired) the revocation version.
+// Refresh token families were already revoked by DeleteUser; DB token rows go with the user row.
+func (s *userService) forgetUser(ctx context.Context, u models.User) error {
+	s.resendMu.Lock()
+	delete(s.resendAt, u.ID)
+	s.resendMu.Unlock()
+	if s.rdb == nil {
+		return nil
+	}
+	email := strings.ToLower(u.Email)
+	if err := s.rdb.Del(ctx,
+		lockoutKey("fail", "acct", email), lockoutKey("lock", "acct", email), lockoutKey("lockcount", "acct", email),
+		fmt.Sprintf("verify:resend:%d", u.ID),
+	).Err(); err != nil {
+		return err
+	}
+	if s.revocations != nil { // Refresh lifetime bounds every token issued before the delete.
+		return s.revocations.Forget(ctx, u.ID, s.refreshTTL)
+	}
+	return nil
+}
+
+// ---------------- Setup ----------------
+
+// BootstrapAdmin makes sure an admin account exists for the configured email.
+// Existing users are promoted; missing ones are created with the given password.
+// Values still holding a "${VAR}" placeholder are refused: config files are not env-expanded.
+func (s *userService) BootstrapAdmin(ctx context.Context, name, email, password string) error {
+	if strings.Contains(email, "${") || strings.Contains(password, "${") {
+		return errors.New("admin_email/admin_password contain an unexpanded ${...} placeholder; set APP_ADMIN_EMAIL and APP_ADMIN_PASSWORD")
+	}
+	u, err := s.repo.FindByEmail(ctx, email)
+	if err == nil { // Already there → just make sure it's an admin.
+		if u.Role == models.RoleAdmin {
+			return nil
+		}
+		u.Role = models.RoleAdmin
+		if err := s.repo.Update(ctx, u); err != nil {
+			return err
+		}
+		s.invalidateUserCache(ctx, u.ID) // Drop stale cached copy.
+		if s.log != nil { s.log.InfoContext(ctx, "admin bootstrap promoted", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+		return nil
+	}
+	if !repositories.IsNotFound(err) { // Real DB error.
+		return err
+	}
+	if exists, _ := s.repo.EmailExists(ctx, email); exists { // Soft-deleted; creating would hit the unique index.
+		return errors.New("admin account is deleted; restore it or change admin_email")
+	}
+	if password == "" { // Refuse to create an admin without a password.
+		return errors.New("admin_password required to create bootstrap admin")
+	}
+	hash, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+	now := time.Now() // Configured admin address is trusted as verified.
+	u = &models.User{Name: core.NormalizeName(name), Email: email, Password: hash, Role: models.RoleAdmin, EmailVerifiedAt: &now}
+	if err := s.repo.Create(ctx, u); err != nil {
+		return err
+	}
+	if s.log != nil { s.log.InfoContext(ctx, "admin bootstrap created", map[string]string{"user_id": fmt.Sprint(u.ID)}) }
+	return nil
+}
@@ -4,6 +4,7 @@
 package services_test
 
 import (
+  "context"
   "os"
   "testing"
 
@@ -43,10 +44,11 @@ func newMySQLService(t *testing.T) services.UserService {
 }
 
 func TestMySQL_CreateRead(t *testing.T) {
+  ctx := context.Background()
   svc := newMySQLService(t)
 
   // Clean-up note: consider truncating table before/after if you re-run often.
-  u, err := svc.CreateUser(models.RegisterRequest{
+  u, err := svc.CreateUser(ctx, models.RegisterRequest{
     Name:     "mysql-user",
     Email:    "mysql-user@example.com",
     Password: "secret123",
@@ -55,7 +57,7 @@ func TestMySQL_CreateRead(t *testing.T) {
     t.Fatalf("create: %v", err)
   }
 
-  got, err := svc.GetUser(u.ID)
+  got, err := svc.GetUser(ctx, u.ID)
   if err != nil {
     t.Fatalf("get: %v", err)
   }
@@ -2,7 +2,12 @@ package services_test // Use _test to exercise the public API like an external c
 
 import ( // Imports for tests.
 	"context" // For Redis calls in assertions (optional).
+	"encoding/json" // Decoding Redis log entries.
+	"errors" // Matching typed service errors.
 	"fmt" // For formatting emails in loop.
+	"os" // Reading the file mailer output.
+	"path/filepath" // Temp mail file path.
+	"regexp" // Extracting tokens from emails.
 	"testing" // Go test framework.
 	"time" // TTL/retention values if needed.
 
@@ -12,53 +17,69 @@ import ( // Imports for tests.
 	"HelmyTask/models" // DTOs and model.
 	"HelmyTask/repositories" // Repo ctor.
 	"HelmyTask/services" // Service ctor.
+	"HelmyTask/utils" // TOTP helper to compute codes.
+	"HelmyTask/utils/jwtauth" // HS256 key set for signing test tokens.
+	"HelmyTask/utils/logging" // Request ID in context.
+	"HelmyTask/utils/mailer" // File mailer to capture outgoing emails.
 	"HelmyTask/utils/redislog" // Redis logger used by the service.
 
 	"gorm.io/driver/sqlite" // In-memory SQLite driver.
 	"gorm.io/gorm" // GORM ORM.
 )
 
-// newTestDeps spins up in-memory DB + fake Redis + Redis logger, returns a ready service and useful handles.
-func newTestDeps(t *testing.T) (services.UserService, *miniredis.Miniredis, *redis.Client) {
-	t.Helper() // Mark as helper so failures point to caller line.
+// testSigner signs access tokens in tests (HS256 shared secret, no kid).
+var testSigner, _ = jwtauth.NewKeySet(jwtauth.NewHMACKey("", "test-secret"))
 
-	// 1) Create a fresh in-memory SQLite DB for this test case.
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{}) // Lives only in memory.
+// newTestDB opens an in-memory SQLite DB named after the test, so tests (and bulk operations
+// like the purge) never see each other's rows; calling it twice in one test returns the same DB.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{}) // Lives only in memory.
 	if err != nil {
 		t.Fatalf("open sqlite: %v", err) // Fail test if DB cannot open.
 	}
-	// 2) Migrate the schema we need (User).
-	if err := db.AutoMigrate(&models.User{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.OneTimeToken{}); err != nil {
 		t.Fatalf("migrate: %v", err) // Fail if migration fails.
 	}
-	// 3) Build the repository against this DB.
+	return db
+}
+
+// newTestDeps spins up in-memory DB + fake Redis + Redis logger, returns a ready service and useful handles.
+// Extra options (mailer, stores, ...) are forwarded to the service constructor.
+func newTestDeps(t *testing.T, opts ...services.Option) (services.UserService, *miniredis.Miniredis, *redis.Client) {
+	t.Helper() // Mark as helper so failures point to caller line.
+
+	// 1) Open this test's in-memory SQLite DB (schema migrated).
+	db := newTestDB(t)
+	// 2) Build the repository against this DB.
 	repo := repositories.NewUserRepository(db) // Concrete repo for tests.
 
-	// 4) Start a fake Redis server in memory (no network).
+	// 3) Start a fake Redis server in memory (no network).
 	mr := miniredis.RunT(t) // Auto-closes when test finishes.
 
-	// 5) Create a Redis client pointed to the fake server.
+	// 4) Create a Redis client pointed to the fake server.
 	rdb := redis.NewClient(&redis.Options{ // Standard go-redis client.
 		Addr: mr.Addr(), // Use address provided by miniredis (127.0.0.1:<port>).
 	})
 
-	// 6) Build a Redis logger that writes into a list key "testlogs:app".
-	rlog := redislog.New(rdb, "testlogs:app", 1000, 7*24*time.Hour) // Keep last 1000; expire in 7 days.
+	// 5) Build a Redis logger that writes into a list key "testlogs:app".
+	rlog := redislog.New(rdb, "testlogs:app", 1000, 7*24*time.Hour, redislog.WithBufferSize(0)) // Keep last 1000; expire in 7 days; write inline so assertions see entries.
 
-	// 7) Construct the service with repo + Redis client + Redis logger.
-	svc := services.NewUserService(repo, rdb, rlog) // This is what we will test.
+	// 6) Construct the service with repo + Redis client + Redis logger.
+	svc := services.NewUserService(repo, rdb, rlog, opts...) // This is what we will test.
 
-	// 8) Return service + fake redis handles to allow assertions on logs.
+	// 7) Return service + fake redis handles to allow assertions on logs.
 	return svc, mr, rdb
 }
 
 func TestCreateAndGetUser_WithRedisLogging(t *testing.T) {
+	ctx := context.Background()
 	// Prepare a service wired with in-memory DB + fake Redis + Redis logger.
 	svc, mr, rdb := newTestDeps(t)
 	_ = mr // Keep the variable to show we own the fake server lifecycle.
 
 	// Create a new user via the service (this should log to Redis and create a DB row).
-	u, err := svc.CreateUser(models.RegisterRequest{
+	u, err := svc.CreateUser(ctx, models.RegisterRequest{
 		Name:     "ahmed", // Service should normalize this (e.g., "Ahmed").
 		Email:    "ahmed@example.com", // Must be unique.
 		Password: "secret123", // Will be hashed by service.
@@ -71,7 +92,7 @@ func TestCreateAndGetUser_WithRedisLogging(t *testing.T) {
 	}
 
 	// Read the same user back (GetUser reuses GetByID, which tries cache first).
-	got, err := svc.GetUser(u.ID)
+	got, err := svc.GetUser(ctx, u.ID)
 	if err != nil {
 		t.Fatalf("get: %v", err) // Should be found.
 	}
@@ -80,7 +101,6 @@ func TestCreateAndGetUser_WithRedisLogging(t *testing.T) {
 	}
 
 	// Assert that some logs were pushed to Redis (LPUSH into "testlogs:app").
-	ctx := context.Background() // Context for Redis calls.
 	n, err := rdb.LLen(ctx, "testlogs:app").Result() // Count entries in Redis logs list.
 	if err != nil {
 		t.Fatalf("redis llen: %v", err) // Should not error against miniredis.
@@ -91,11 +111,12 @@ func TestCreateAndGetUser_WithRedisLogging(t *testing.T) {
 }
 
 func TestUpdateAndDeleteUser_WithRedisLogging(t *testing.T) {
+	ctx := context.Background()
 	// Build service with fake redis logger as before.
 	svc, _, rdb := newTestDeps(t)
 
 	// Seed a user to update/delete.
-	u, err := svc.CreateUser(models.RegisterRequest{
+	u, err := svc.CreateUser(ctx, models.RegisterRequest{
 		Name:     "mona",
 		Email:    "mona@example.com",
 		Password: "pass1234",
@@ -107,7 +128,7 @@ func TestUpdateAndDeleteUser_WithRedisLogging(t *testing.T) {
 	// Update the user's name and password (logs should record "UpdateUser called" and cache refresh).
 	newName := "Mona Lisa"
 	newPass := "newpass567"
-	upd, err := svc.UpdateUser(u.ID, models.UpdateUserRequest{
+	upd, err := svc.UpdateUser(ctx, u.ID, models.UpdateUserRequest{
 		Name:     &newName, // Partial update: only name + password.
 		Password: &newPass, // Service will hash it.
 	})
@@ -119,24 +140,90 @@ func TestUpdateAndDeleteUser_WithRedisLogging(t *testing.T) {
 	}
 
 	// Delete the user (logs should record "DeleteUser called" and success).
-	if err := svc.DeleteUser(u.ID); err != nil {
+	if err := svc.DeleteUser(ctx, u.ID); err != nil {
 		t.Fatalf("delete: %v", err)
 	}
 
 	// Ensure logs list has grown (very light assertion just to prove writes happened).
-		if l, _ := rdb.LLen(context.Background(), "testlogs:app").Result(); l == 0 {
+		if l, _ := rdb.LLen(ctx, "testlogs:app").Result(); l == 0 {
 		t.Fatalf("expected redis logs after update/delete")
 	}
 
 }
 
+func TestUpdateUser_PasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t)
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "rania", Email: "rania@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	resp, err := svc.Login(ctx, models.LoginRequest{Email: "rania@example.com", Password: "secret123"}, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Admin reset of the password: old sessions end, as with ChangePassword.
+	newPass := "newpass567"
+	if _, err := svc.UpdateUser(ctx, u.ID, models.UpdateUserRequest{Password: &newPass}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-old", u.ID, 0); !revoked {
+		t.Fatalf("expected tokens from before the reset to be revoked")
+	}
+	if _, err := svc.Refresh(ctx, resp.RefreshToken, testSigner, time.Minute); err == nil {
+		t.Fatalf("expected refresh token to be revoked by the password reset")
+	}
+}
+
+func TestChangePassword_ChecksCurrentAndRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t)
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "omar", Email: "omar.pw@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	resp, err := svc.Login(ctx, models.LoginRequest{Email: "omar.pw@example.com", Password: "secret123"}, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Wrong current password: rejected, nothing changes, sessions stay valid.
+	if err := svc.ChangePassword(ctx, u.ID, "wrong-pass", "newpass567"); err == nil {
+		t.Fatalf("expected wrong current password to be rejected")
+	}
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-old", u.ID, 0); revoked {
+		t.Fatalf("a rejected change must not revoke sessions")
+	}
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "omar.pw@example.com", Password: "secret123"}, testSigner, time.Minute); err != nil {
+		t.Fatalf("old password should still work after a rejected change: %v", err)
+	}
+
+	// Correct current password: new password works and every older session ends.
+	if err := svc.ChangePassword(ctx, u.ID, "secret123", "newpass567"); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-old", u.ID, 0); !revoked {
+		t.Fatalf("expected tokens from before the change to be revoked")
+	}
+	if _, err := svc.Refresh(ctx, resp.RefreshToken, testSigner, time.Minute); err == nil {
+		t.Fatalf("expected refresh token to be revoked by the password change")
+	}
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "omar.pw@example.com", Password: "newpass567"}, testSigner, time.Minute); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
+
 func TestListUsers_WithRedisLogging(t *testing.T) {
+	ctx := context.Background()
 	// Fresh service and fake redis.
 	svc, _, _ := newTestDeps(t)
 
 	// Seed multiple users.
 	for i := 0; i < 5; i++ {
-		_, err := svc.CreateUser(models.RegisterRequest{
+		_, err := svc.CreateUser(ctx, models.RegisterRequest{
 			Name:     fmt.Sprintf("u%d", i), // Distinct name per user.
 			Email:    fmt.Sprintf("u%d@ex.com", i), // Distinct email per user.
 			Password: "p123456", // Arbitrary valid password.
@@ -147,14 +234,602 @@ func TestListUsers_WithRedisLogging(t *testing.T) {
 	}
 
 	// List page 1, limit 2 (should get exactly 2 items, total >= 5).
-	page, err := svc.ListUsers(1, 2)
+	page, err := svc.ListUsers(ctx, models.ListUserQuery{Page: 1, Limit: 2})
 	if err != nil {
 		t.Fatalf("list p1: %v", err)
 	}
 	if len(page.Items) != 2 {
 		t.Fatalf("expected 2 items on page 1, got %d", len(page.Items))
 	}
-	if page.Total < 5 {
-		t.Fatalf("expected total >= 5, got %d", page.Total)
+	if *page.Total < 5 {
+		t.Fatalf("expected total >= 5, got %d", *page.Total)
+	}
+}
+
+func TestRefreshRotation_ReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	// Refresh tokens go to (fake) Redis by default.
+	svc, _, _ := newTestDeps(t)
+
+	// Seed and log in to get the first pair.
+	if _, err := svc.Register(ctx, models.RegisterRequest{Name: "rita", Email: "rita@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	first, err := svc.Login(ctx, models.LoginRequest{Email: "rita@example.com", Password: "secret123"}, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if first.Token == "" || first.RefreshToken == "" {
+		t.Fatalf("expected access + refresh token, got %+v", first)
+	}
+
+	// Rotating once works and yields a different refresh token.
+	second, err := svc.Refresh(ctx, first.RefreshToken, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a rotated refresh token")
+	}
+
+	// Replaying the spent token is reuse → error, and the family is revoked.
+	if _, err := svc.Refresh(ctx, first.RefreshToken, testSigner, time.Minute); err != services.ErrRefreshTokenReused {
+		t.Fatalf("expected reuse error, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, testSigner, time.Minute); err != services.ErrInvalidRefreshToken {
+		t.Fatalf("expected family revoked, got %v", err)
+	}
+}
+
+func TestLogoutAndLogoutAll_RevokeTokens(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t)
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "omar", Email: "omar@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	resp, err := svc.Login(ctx, models.LoginRequest{Email: "omar@example.com", Password: "secret123"}, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Logging out one token deny-lists its jti and kills its refresh token.
+	if err := svc.Logout(ctx, u.ID, "jti-1", time.Now().Add(time.Minute), resp.RefreshToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-1", u.ID, 0); !revoked {
+		t.Fatalf("expected jti to be revoked")
+	}
+	if _, err := svc.Refresh(ctx, resp.RefreshToken, testSigner, time.Minute); err == nil {
+		t.Fatalf("expected refresh token to be revoked by logout")
+	}
+
+	// Logout everywhere invalidates any token minted with the old version.
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-2", u.ID, 0); revoked {
+		t.Fatalf("unrelated token should still be valid")
+	}
+	if err := svc.LogoutAll(ctx, u.ID); err != nil {
+		t.Fatalf("logout all: %v", err)
+	}
+	if revoked, _ := svc.IsTokenRevoked(ctx, "jti-2", u.ID, 0); !revoked {
+		t.Fatalf("expected old-version token to be revoked")
+	}
+}
+
+func TestBootstrapAdmin_CreatesThenPromotes(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t)
+
+	// Unexpanded config placeholders must never become a real admin.
+	if err := svc.BootstrapAdmin(ctx, "root", "${ADMIN_EMAIL}", "${ADMIN_PASSWORD}"); err == nil {
+		t.Fatal("expected placeholder admin settings to be refused")
+	}
+
+	// Missing account → created as admin.
+	if err := svc.BootstrapAdmin(ctx, "root", "root-admin@example.com", "secret123"); err != nil {
+		t.Fatalf("bootstrap create: %v", err)
+	}
+	resp, err := svc.Login(ctx, models.LoginRequest{Email: "root-admin@example.com", Password: "secret123"}, testSigner, time.Minute)
+	if err != nil || resp.Token == "" {
+		t.Fatalf("admin login: %v", err)
+	}
+
+	// Existing regular user → promoted in place.
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "sara", Email: "sara@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if u.Role != models.RoleUser {
+		t.Fatalf("expected registered user to have role %q, got %q", models.RoleUser, u.Role)
+	}
+	if err := svc.BootstrapAdmin(ctx, "", "sara@example.com", ""); err != nil {
+		t.Fatalf("bootstrap promote: %v", err)
+	}
+	got, err := svc.GetUser(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Role != models.RoleAdmin {
+		t.Fatalf("expected promoted role admin, got %q", got.Role)
+	}
+}
+
+// lastMailToken returns the last token-looking line written by the file mailer.
+func lastMailToken(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mail file: %v", err)
+	}
+	m := regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{40,}$`).FindAll(b, -1)
+	if len(m) == 0 {
+		t.Fatalf("no token found in mail file:\n%s", b)
+	}
+	return string(m[len(m)-1])
+}
+
+func TestPasswordReset_SingleUseAndRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	mailPath := filepath.Join(t.TempDir(), "mail.log")
+	svc, _, _ := newTestDeps(t, services.WithMailer(mailer.NewFileMailer(mailPath)))
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "nour", Email: "nour@example.com", Password: "oldpass1"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	login, err := svc.Login(ctx, models.LoginRequest{Email: "nour@example.com", Password: "oldpass1"}, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Unknown emails are silently accepted.
+	if err := svc.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("forgot unknown: %v", err)
+	}
+	if err := svc.ForgotPassword(ctx, "nour@example.com"); err != nil {
+		t.Fatalf("forgot: %v", err)
+	}
+	token := lastMailToken(t, mailPath)
+
+	if err := svc.ResetPassword(ctx, token, "newpass1"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	// Second use of the same token fails.
+	if err := svc.ResetPassword(ctx, token, "another1"); err != services.ErrInvalidResetToken {
+		t.Fatalf("expected single-use token, got %v", err)
+	}
+	// Old sessions are gone, new password works.
+	if revoked, _ := svc.IsTokenRevoked(ctx, "any", u.ID, 0); !revoked {
+		t.Fatalf("expected existing sessions to be revoked")
+	}
+	if _, err := svc.Refresh(ctx, login.RefreshToken, testSigner, time.Minute); err == nil {
+		t.Fatalf("expected refresh token to be revoked")
+	}
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "nour@example.com", Password: "newpass1"}, testSigner, time.Minute); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
+
+func TestEmailVerification_RequiredForLogin(t *testing.T) {
+	ctx := context.Background()
+	mailPath := filepath.Join(t.TempDir(), "mail.log")
+	svc, _, _ := newTestDeps(t,
+		services.WithMailer(mailer.NewFileMailer(mailPath)),
+		services.WithRequireVerifiedEmail(true),
+	)
+
+	if _, err := svc.Register(ctx, models.RegisterRequest{Name: "laila", Email: "laila@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	creds := models.LoginRequest{Email: "laila@example.com", Password: "secret123"}
+	if _, err := svc.Login(ctx, creds, testSigner, time.Minute); err != services.ErrEmailNotVerified {
+		t.Fatalf("expected unverified login to fail, got %v", err)
+	}
+
+	// Immediate resend is throttled (registration just sent one): same answer, no new mail.
+	sent, _ := os.ReadFile(mailPath)
+	if err := svc.ResendVerification(ctx, "laila@example.com"); err != nil {
+		t.Fatalf("throttled resend must look like success, got %v", err)
+	}
+	if after, _ := os.ReadFile(mailPath); len(after) != len(sent) {
+		t.Fatalf("expected no second verification email while throttled")
+	}
+	if err := svc.ResendVerification(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email must look like success, got %v", err)
+	}
+
+	u, err := svc.VerifyEmail(ctx, lastMailToken(t, mailPath))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if u.EmailVerifiedAt == nil {
+		t.Fatalf("expected email_verified_at to be set")
+	}
+	if _, err := svc.Login(ctx, creds, testSigner, time.Minute); err != nil {
+		t.Fatalf("login after verify: %v", err)
+	}
+}
+
+func TestEmailVerification_TokenBoundToAddress(t *testing.T) {
+	ctx := context.Background()
+	mailPath := filepath.Join(t.TempDir(), "mail.log")
+	svc, _, _ := newTestDeps(t, services.WithMailer(mailer.NewFileMailer(mailPath)))
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "samir", Email: "samir@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	oldToken := lastMailToken(t, mailPath) // Mailed to samir@example.com.
+
+	// Switch to someone else's address, then redeem the token of the old one.
+	victim := "victim@example.com"
+	if _, err := svc.UpdateUser(ctx, u.ID, models.UpdateUserRequest{Email: &victim}); err != nil {
+		t.Fatalf("update email: %v", err)
+	}
+	newToken := lastMailToken(t, mailPath) // Mailed to the new address.
+	if _, err := svc.VerifyEmail(ctx, oldToken); !errors.Is(err, services.ErrInvalidVerifyToken) {
+		t.Fatalf("expected token of the previous address to be rejected, got %v", err)
+	}
+	if got, _ := svc.GetByID(ctx, u.ID); got == nil || got.EmailVerifiedAt != nil {
+		t.Fatalf("new address must stay unverified, got %+v", got)
+	}
+
+	// Only the token mailed to the current address verifies it.
+	got, err := svc.VerifyEmail(ctx, newToken)
+	if err != nil || got.EmailVerifiedAt == nil {
+		t.Fatalf("verify with new token: %v", err)
+	}
+}
+
+func TestTOTP_TwoStepLoginAndRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	svc, mr, _ := newTestDeps(t)
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "hany", Email: "hany@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	enr, err := svc.EnrollTOTP(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	code, _ := utils.TOTPCode(enr.Secret, time.Now())
+	recovery, err := svc.ConfirmTOTP(ctx, u.ID, code)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(recovery) == 0 {
+		t.Fatalf("expected recovery codes")
+	}
+
+	// Password alone now yields a challenge, not a token.
+	creds := models.LoginRequest{Email: "hany@example.com", Password: "secret123"}
+	ch, err := svc.Login(ctx, creds, testSigner, time.Minute)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !ch.MFARequired || ch.Token != "" || ch.MFAToken == "" {
+		t.Fatalf("expected mfa challenge, got %+v", ch)
+	}
+	// The confirm code was already used once (replay guard); let it expire.
+	mr.FastForward(2 * time.Minute)
+	code, _ = utils.TOTPCode(enr.Secret, time.Now())
+	resp, err := svc.VerifyMFA(ctx, ch.MFAToken, code, testSigner, time.Minute)
+	if err != nil || resp.Token == "" {
+		t.Fatalf("verify mfa: %v", err)
+	}
+
+	// Recovery codes work once.
+	ch, _ = svc.Login(ctx, creds, testSigner, time.Minute)
+	if _, err := svc.VerifyMFA(ctx, ch.MFAToken, recovery[0], testSigner, time.Minute); err != nil {
+		t.Fatalf("verify with recovery code: %v", err)
+	}
+	ch, _ = svc.Login(ctx, creds, testSigner, time.Minute)
+	if _, err := svc.VerifyMFA(ctx, ch.MFAToken, recovery[0], testSigner, time.Minute); err != services.ErrMFAInvalidCode {
+		t.Fatalf("expected used recovery code to fail, got %v", err)
+	}
+}
+
+func TestTOTP_WrongCodesLockAccount(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t, services.WithLockout(services.LockoutConfig{
+		MaxAccountFailures: 3, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour,
+	}))
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "reem", Email: "reem@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	enr, _ := svc.EnrollTOTP(ctx, u.ID)
+	code, _ := utils.TOTPCode(enr.Secret, time.Now())
+	if _, err := svc.ConfirmTOTP(ctx, u.ID, code); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	// Knowing the password must not buy unlimited code guesses: each correct password
+	// starts a new challenge but no longer resets the counter.
+	creds := models.LoginRequest{Email: "reem@example.com", Password: "secret123"}
+	var lastErr error
+	for i := 0; i < 3; i++ {
+		ch, err := svc.Login(ctx, creds, testSigner, time.Minute)
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		_, lastErr = svc.VerifyMFA(ctx, ch.MFAToken, "000000-wrong", testSigner, time.Minute)
+	}
+	var locked *services.LockedError
+	if !errors.As(lastErr, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("expected the third wrong code to lock the account, got %v", lastErr)
+	}
+	if _, err := svc.Login(ctx, creds, testSigner, time.Minute); !errors.As(err, &locked) {
+		t.Fatalf("expected login to stay locked after wrong codes, got %v", err)
+	}
+}
+
+func TestLoginLockout_BackoffAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	svc, mr, _ := newTestDeps(t, services.WithLockout(services.LockoutConfig{
+		MaxAccountFailures: 3, MaxIPFailures: 100, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: 10 * time.Minute,
+	}))
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "tarek", Email: "tarek@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	bad := models.LoginRequest{Email: "tarek@example.com", Password: "wrong", ClientIP: "10.0.0.1"}
+	good := models.LoginRequest{Email: "tarek@example.com", Password: "secret123", ClientIP: "10.0.0.1"}
+
+	// Two plain failures, the third locks for BaseLockout.
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Login(ctx, bad, testSigner, time.Minute); err == nil || errors.Is(err, services.ErrLoginLocked) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+	var locked *services.LockedError
+	if _, err := svc.Login(ctx, bad, testSigner, time.Minute); !errors.As(err, &locked) || locked.RetryAfter != time.Minute {
+		t.Fatalf("expected 1m lock, got %v", err)
+	}
+	// Even the right password is refused while locked.
+	if _, err := svc.Login(ctx, good, testSigner, time.Minute); !errors.Is(err, services.ErrLoginLocked) {
+		t.Fatalf("expected locked login, got %v", err)
+	}
+
+	// After the lock expires, the next lock doubles.
+	mr.FastForward(time.Minute + time.Second)
+	for i := 0; i < 3; i++ {
+		_, err = svc.Login(ctx, bad, testSigner, time.Minute)
+	}
+	if !errors.As(err, &locked) || locked.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected 2m lock, got %v", err)
+	}
+
+	// Admin unlock lifts it immediately.
+	if err := svc.UnlockUser(ctx, u.ID); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if _, err := svc.Login(ctx, good, testSigner, time.Minute); err != nil {
+		t.Fatalf("login after unlock: %v", err)
+	}
+}
+
+func TestSoftDelete_RestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t) // Same DB the service uses; token rows are stored here so the purge can be checked.
+	svc, mr, _ := newTestDeps(t,
+		services.WithRefreshTokenRepository(repositories.NewRefreshTokenRepository(db)),
+		services.WithOneTimeTokenRepository(repositories.NewOneTimeTokenRepository(db)),
+		services.WithLockout(services.LockoutConfig{MaxAccountFailures: 5, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}),
+	)
+
+	u, err := svc.Register(ctx, models.RegisterRequest{Name: "rana", Email: "rana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, err := svc.GetByID(ctx, u.ID); err != nil { // Warm the cache so delete has something to invalidate.
+		t.Fatalf("get: %v", err)
+	}
+	if err := svc.DeleteUser(ctx, u.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetByID(ctx, u.ID); !repositories.IsNotFound(err) {
+		t.Fatalf("expected deleted user to be hidden (cache dropped), got %v", err)
+	}
+	// The email stays taken while the account can still be restored.
+	if _, err := svc.Register(ctx, models.RegisterRequest{Name: "rana", Email: "rana@example.com", Password: "secret123"}); err == nil {
+		t.Fatalf("expected email of deleted user to stay reserved")
+	}
+
+	deleted, err := svc.ListDeletedUsers(ctx, 1, 100)
+	if err != nil {
+		t.Fatalf("list deleted: %v", err)
+	}
+	found := false
+	for _, d := range deleted.Items {
+		found = found || d.ID == u.ID
+	}
+	if !found {
+		t.Fatalf("expected user %d in deleted list", u.ID)
+	}
+
+	if _, err := svc.RestoreUser(ctx, u.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, err := svc.GetByID(ctx, u.ID); err != nil {
+		t.Fatalf("get after restore: %v", err)
+	}
+
+	// Leave state behind that the purge has to clean up: tokens, a lockout counter, the resend throttle.
+	if _, err := svc.Login(ctx, models.LoginRequest{Email: "rana@example.com", Password: "secret123"}, testSigner, time.Minute); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if err := svc.ForgotPassword(ctx, "rana@example.com"); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	_, _ = svc.Login(ctx, models.LoginRequest{Email: "rana@example.com", Password: "wrong-pass"}, testSigner, time.Minute)
+	if !mr.Exists("login:fail:acct:rana@example.com") || !mr.Exists(fmt.Sprintf("verify:resend:%d", u.ID)) {
+		t.Fatalf("expected lockout counter and resend throttle before the purge")
+	}
+
+	// Purge leaves recent deletions alone and removes them once past retention.
+	if err := svc.DeleteUser(ctx, u.ID); err != nil {
+		t.Fatalf("delete again: %v", err)
+	}
+	if _, err := svc.PurgeDeletedUsers(ctx, time.Hour); err != nil {
+		t.Fatalf("purge (retention 1h): %v", err)
+	}
+	if _, err := svc.RestoreUser(ctx, u.ID); err != nil {
+		t.Fatalf("expected user to survive purge within retention: %v", err)
+	}
+	_ = svc.DeleteUser(ctx, u.ID)
+	if _, err := svc.PurgeDeletedUsers(ctx, -time.Second); err != nil { // Cutoff in the future → everything deleted so far.
+		t.Fatalf("purge: %v", err)
+	}
+	if _, err := svc.RestoreUser(ctx, u.ID); !repositories.IsNotFound(err) {
+		t.Fatalf("expected purged user to be gone, got %v", err)
+	}
+	var refreshRows, oneTimeRows int64
+	db.Model(&models.RefreshToken{}).Where("user_id = ?", u.ID).Count(&refreshRows)
+	db.Model(&models.OneTimeToken{}).Where("user_id = ?", u.ID).Count(&oneTimeRows)
+	if refreshRows != 0 || oneTimeRows != 0 {
+		t.Fatalf("expected token rows to be purged, got refresh=%d one_time=%d", refreshRows, oneTimeRows)
+	}
+	if mr.Exists("login:fail:acct:rana@example.com") || mr.Exists(fmt.Sprintf("verify:resend:%d", u.ID)) {
+		t.Fatalf("expected lockout and resend keys to be purged")
+	}
+	if ttl := mr.TTL(fmt.Sprintf("auth:ver:%d", u.ID)); ttl <= 0 { // Kept until older tokens expire, then gone.
+		t.Fatalf("expected the revocation version to expire after the purge, ttl=%v", ttl)
+	}
+}
+
+func TestListUsers_FilterSortSearch(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t)
+
+	for _, n := range []string{"alpha", "bravo", "charlie"} {
+		if _, err := svc.Register(ctx, models.RegisterRequest{Name: n + " fltr", Email: n + ".fltr@example.com", Password: "secret123"}); err != nil {
+			t.Fatalf("seed %s: %v", n, err)
+		}
+	}
+
+	// Case-insensitive search over name/email, sorted by name descending.
+	page, err := svc.ListUsers(ctx, models.ListUserQuery{Q: "FLTR", Sort: "-name", Limit: 10})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if *page.Total != 3 || len(page.Items) != 3 || page.Items[0].Email != "charlie.fltr@example.com" {
+		t.Fatalf("unexpected search result: total=%d items=%v", *page.Total, page.Items)
+	}
+
+	// Filters combine with AND; LIKE wildcards in the term are matched literally.
+	page, _ = svc.ListUsers(ctx, models.ListUserQuery{Email: "bravo", Role: models.RoleUser, Status: models.UserStatusUnverified})
+	if *page.Total != 1 {
+		t.Fatalf("expected 1 match for bravo, got %d", *page.Total)
+	}
+	page, _ = svc.ListUsers(ctx, models.ListUserQuery{Q: "fl%r"})
+	if *page.Total != 0 {
+		t.Fatalf("expected %% to be literal, got %d matches", *page.Total)
+	}
+	page, _ = svc.ListUsers(ctx, models.ListUserQuery{Q: "fltr", CreatedFrom: time.Now().Add(time.Hour)})
+	if *page.Total != 0 {
+		t.Fatalf("expected no users created in the future, got %d", *page.Total)
+	}
+
+	if _, err := svc.ListUsers(ctx, models.ListUserQuery{Sort: "password"}); !errors.Is(err, repositories.ErrInvalidSort) {
+		t.Fatalf("expected ErrInvalidSort, got %v", err)
+	}
+}
+
+func TestListUsers_CursorPaging(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t, services.WithCursorSecret([]byte("cursor-test")))
+
+	for i := 0; i < 5; i++ {
+		if _, err := svc.Register(ctx, models.RegisterRequest{Name: fmt.Sprintf("crsr %d", i), Email: fmt.Sprintf("crsr%d@example.com", i), Password: "secret123"}); err != nil {
+			t.Fatalf("seed %d: %v", i, err)
+		}
+	}
+	q := models.ListUserQuery{Q: "crsr", Sort: "-email", Limit: 2, Paging: models.PagingCursor}
+
+	p1, err := svc.ListUsers(ctx, q)
+	if err != nil {
+		t.Fatalf("page 1: %v", err)
+	}
+	if p1.Total != nil || p1.PrevCursor != "" || p1.NextCursor == "" || p1.Items[0].Email != "crsr4@example.com" {
+		t.Fatalf("unexpected first page: %+v", p1)
+	}
+
+	// A row inserted ahead of the cursor does not shift the next page.
+	if _, err := svc.Register(ctx, models.RegisterRequest{Name: "crsr 9", Email: "crsr9@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	q.Cursor, q.WithTotal = p1.NextCursor, true
+	p2, err := svc.ListUsers(ctx, q)
+	if err != nil {
+		t.Fatalf("page 2: %v", err)
+	}
+	if len(p2.Items) != 2 || p2.Items[0].Email != "crsr2@example.com" || p2.Total == nil || *p2.Total != 6 {
+		t.Fatalf("unexpected second page: %+v", p2)
+	}
+
+	// Going back returns page 1 in the original order.
+	q.Cursor = p2.PrevCursor
+	back, err := svc.ListUsers(ctx, q)
+	if err != nil {
+		t.Fatalf("prev: %v", err)
+	}
+	if len(back.Items) != 2 || back.Items[0].Email != "crsr4@example.com" || back.Items[1].Email != "crsr3@example.com" {
+		t.Fatalf("unexpected prev page: %+v", back.Items)
+	}
+
+	// Tampered cursors and cursors reused with a different sort are rejected.
+	q.Cursor = p1.NextCursor[:len(p1.NextCursor)-2] + "xx"
+	if _, err := svc.ListUsers(ctx, q); !errors.Is(err, services.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for tampered cursor, got %v", err)
+	}
+	q.Cursor, q.Sort = p1.NextCursor, "name"
+	if _, err := svc.ListUsers(ctx, q); !errors.Is(err, services.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for changed sort, got %v", err)
+	}
+}
+
+func TestLogin_CancelledContextIsNotAFailedAttempt(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newTestDeps(t, services.WithLockout(services.LockoutConfig{
+		MaxAccountFailures: 1, MaxIPFailures: 100, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Minute,
+	}))
+	if _, err := svc.Register(ctx, models.RegisterRequest{Name: "salma", Email: "salma@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel() // Client went away before the lookup.
+	req := models.LoginRequest{Email: "salma@example.com", Password: "secret123", ClientIP: "10.0.0.2"}
+	if _, err := svc.Login(cancelled, req, testSigner, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	// One failure would have locked the account; the real login still works.
+	if _, err := svc.Login(ctx, req, testSigner, time.Minute); err != nil {
+		t.Fatalf("login after cancelled attempt: %v", err)
+	}
+}
+
+func TestRedisLog_CarriesRequestID(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "req-42") // What middlewares.RequestID stores.
+	svc, _, rdb := newTestDeps(t)
+
+	if _, err := svc.CreateUser(ctx, models.RegisterRequest{Name: "lina", Email: "lina@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	raw, err := rdb.LIndex(ctx, "testlogs:app", 0).Result() // Newest entry.
+	if err != nil {
+		t.Fatalf("redis lindex: %v", err)
+	}
+	var en redislog.Entry
+	if err := json.Unmarshal([]byte(raw), &en); err != nil {
+		t.Fatalf("decode entry: %v", err)
+	}
+	if en.Meta["request_id"] != "req-42" {
+		t.Fatalf("expected request_id in meta, got %v", en.Meta)
 	}
 }
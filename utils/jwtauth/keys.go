@@ -0,0 +1,227 @@
+// Package jwtauth holds the keys used to sign and verify our JWTs.
+// HS256 (shared secret) and asymmetric RS256/ES256/EdDSA keys share one KeySet,
+// tokens carry a "kid" header, and retired keys can stay verify-only during rotation.
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer mints a compact JWT from claims. *KeySet implements it.
+type Signer interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// Key is one signing/verification key identified by its kid.
+type Key struct {
+	KID    string
+	Method jwt.SigningMethod
+	sign   any // private key or HMAC secret; nil for verify-only keys
+	verify any // public key or HMAC secret
+}
+
+// CanSign reports whether the key holds private material.
+func (k *Key) CanSign() bool { return k.sign != nil }
+
+// NewHMACKey wraps a shared secret as an HS256 key.
+func NewHMACKey(kid, secret string) *Key {
+	b := []byte(secret)
+	return &Key{KID: kid, Method: jwt.SigningMethodHS256, sign: b, verify: b}
+}
+
+// LoadKey reads an asymmetric key from PEM files. With a private key file the key can sign
+// (its public half is derived); with only a public key file it can only verify.
+func LoadKey(kid, alg, privateFile, publicFile string) (*Key, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || strings.HasPrefix(alg, "HS") {
+		return nil, fmt.Errorf("jwtauth: unsupported asymmetric alg %q", alg)
+	}
+	k := &Key{KID: kid, Method: method}
+	if privateFile != "" {
+		pem, err := os.ReadFile(privateFile)
+		if err != nil {
+			return nil, err
+		}
+		if k.sign, k.verify, err = parsePrivate(alg, pem); err != nil {
+			return nil, fmt.Errorf("jwtauth: key %q: %w", kid, err)
+		}
+		return k, nil
+	}
+	if publicFile == "" {
+		return nil, fmt.Errorf("jwtauth: key %q has no key file", kid)
+	}
+	pem, err := os.ReadFile(publicFile)
+	if err != nil {
+		return nil, err
+	}
+	if k.verify, err = parsePublic(alg, pem); err != nil {
+		return nil, fmt.Errorf("jwtauth: key %q: %w", kid, err)
+	}
+	return k, nil
+}
+
+// parsePrivate returns (private, public) for the alg family.
+func parsePrivate(alg string, pem []byte) (any, any, error) {
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		k, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, &k.PublicKey, nil
+	case strings.HasPrefix(alg, "ES"):
+		k, err := jwt.ParseECPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, &k.PublicKey, nil
+	case alg == "EdDSA":
+		k, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, k.(ed25519.PrivateKey).Public(), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported alg %q", alg)
+}
+
+// parsePublic returns the public key for the alg family.
+func parsePublic(alg string, pem []byte) (any, error) {
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		return jwt.ParseRSAPublicKeyFromPEM(pem)
+	case strings.HasPrefix(alg, "ES"):
+		return jwt.ParseECPublicKeyFromPEM(pem)
+	case alg == "EdDSA":
+		return jwt.ParseEdPublicKeyFromPEM(pem)
+	}
+	return nil, fmt.Errorf("unsupported alg %q", alg)
+}
+
+// errAlg marks tokens rejected because of their "alg" header.
+var errAlg = errors.New("jwtauth: algorithm not allowed")
+
+// KeySet signs with one active key and verifies with any known key.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	order  []string // stable order for JWKS output
+}
+
+// NewKeySet builds a set; active must be able to sign. Extra keys are accepted for verification only.
+func NewKeySet(active *Key, others ...*Key) (*KeySet, error) {
+	if active == nil || !active.CanSign() {
+		return nil, errors.New("jwtauth: active key must have private material")
+	}
+	ks := &KeySet{active: active, keys: map[string]*Key{}}
+	for _, k := range append([]*Key{active}, others...) {
+		if _, dup := ks.keys[k.KID]; dup {
+			return nil, fmt.Errorf("jwtauth: duplicate kid %q", k.KID)
+		}
+		ks.keys[k.KID] = k
+		ks.order = append(ks.order, k.KID)
+	}
+	return ks, nil
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(ks.active.Method, claims)
+	if ks.active.KID != "" {
+		t.Header["kid"] = ks.active.KID
+	}
+	return t.SignedString(ks.active.sign)
+}
+
+// Keyfunc picks the verification key by kid and refuses tokens whose alg does not match that key.
+// Tokens without kid use the key registered without one (e.g. a retired HS256 secret), else the active key.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		if kid != "" {
+			return nil, fmt.Errorf("jwtauth: unknown kid %q", kid)
+		}
+		k = ks.active
+	}
+	if t.Method.Alg() != k.Method.Alg() { // Blocks alg-confusion (e.g. HS256 signed with a public key).
+		return nil, fmt.Errorf("%w: %q for kid %q", errAlg, t.Method.Alg(), k.KID)
+	}
+	return k.verify, nil
+}
+
+// Algorithms lists the distinct algs of all keys (the default allow-list for a Validator).
+func (ks *KeySet) Algorithms() []string {
+	var algs []string
+	seen := map[string]bool{}
+	for _, kid := range ks.order {
+		if alg := ks.keys[kid].Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// Parse verifies a token and returns its claims.
+func (ks *KeySet) Parse(raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, ks.Keyfunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ---------------- JWKS ----------------
+
+// JWK is the public part of one key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // EC / OKP curve
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys of every asymmetric key; HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	doc := JWKS{Keys: []JWK{}}
+	b64 := base64.RawURLEncoding.EncodeToString
+	for _, kid := range ks.order {
+		k := ks.keys[kid]
+		j := JWK{Kid: k.KID, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			j.Kty, j.N, j.E = "RSA", b64(pub.N.Bytes()), b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			j.Kty, j.Crv = "EC", pub.Curve.Params().Name
+			j.X, j.Y = b64(pub.X.FillBytes(make([]byte, size))), b64(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			j.Kty, j.Crv, j.X = "OKP", "Ed25519", b64(pub)
+		default: // HMAC → secret, skip
+			continue
+		}
+		doc.Keys = append(doc.Keys, j)
+	}
+	return doc
+}
@@ -0,0 +1,157 @@
+package jwtauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeEdKeys writes a fresh Ed25519 key pair as PEM files and returns their paths.
+func writeEdKeys(t *testing.T, name string) (priv, pub string) {
+	t.Helper()
+	pk, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	skDER, _ := x509.MarshalPKCS8PrivateKey(sk)
+	pkDER, _ := x509.MarshalPKIXPublicKey(pk)
+	dir := t.TempDir()
+	priv, pub = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".pub.pem")
+	_ = os.WriteFile(priv, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: skDER}), 0o600)
+	_ = os.WriteFile(pub, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkDER}), 0o600)
+	return priv, pub
+}
+
+func TestKeySet_RotationAndJWKS(t *testing.T) {
+	oldPriv, oldPub := writeEdKeys(t, "old")
+	newPriv, _ := writeEdKeys(t, "new")
+
+	// Token signed before rotation with the old key.
+	oldKey, err := LoadKey("old", "EdDSA", oldPriv, "")
+	if err != nil {
+		t.Fatalf("load old: %v", err)
+	}
+	before, _ := NewKeySet(oldKey)
+	tok, err := before.Sign(jwt.MapClaims{"sub": 1})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// After rotation: new key signs, old key is verify-only.
+	newKey, err := LoadKey("new", "EdDSA", newPriv, "")
+	if err != nil {
+		t.Fatalf("load new: %v", err)
+	}
+	oldVerify, err := LoadKey("old", "EdDSA", "", oldPub)
+	if err != nil {
+		t.Fatalf("load old public: %v", err)
+	}
+	after, err := NewKeySet(newKey, oldVerify)
+	if err != nil {
+		t.Fatalf("keyset: %v", err)
+	}
+	if _, err := after.Parse(tok); err != nil {
+		t.Fatalf("old token should still verify: %v", err)
+	}
+	if doc := after.JWKS(); len(doc.Keys) != 2 || doc.Keys[0].Kid != "new" || doc.Keys[0].Kty != "OKP" {
+		t.Fatalf("unexpected jwks: %+v", doc)
+	}
+
+	// An HS256 token claiming the same kid must not be accepted (alg confusion).
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": 1})
+	forged.Header["kid"] = "new"
+	raw, _ := forged.SignedString([]byte("whatever"))
+	if _, err := after.Parse(raw); err == nil {
+		t.Fatalf("expected alg mismatch to be rejected")
+	}
+}
+
+func TestKeySet_RetiredHMACSecretStillVerifies(t *testing.T) {
+	now := time.Now()
+	claims := func() jwt.MapClaims { return jwt.MapClaims{"sub": 1, "exp": now.Add(time.Minute).Unix()} }
+
+	// Issued while HS256 with jwt_secret (no kid) was in use.
+	before, _ := NewKeySet(NewHMACKey("", "old-secret"))
+	old, err := NewValidator(before, ValidatorConfig{}).Sign(claims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// Switched to EdDSA; the old secret stays verify-only.
+	priv, _ := writeEdKeys(t, "ed")
+	edKey, err := LoadKey("ed-1", "EdDSA", priv, "")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	after, err := NewKeySet(edKey, NewHMACKey("", "old-secret"))
+	if err != nil {
+		t.Fatalf("keyset: %v", err)
+	}
+	v := NewValidator(after, ValidatorConfig{})
+	if _, err := v.Parse(old); err != nil {
+		t.Fatalf("token from before the switch should still verify: %v", err)
+	}
+	fresh, _ := v.Sign(claims())
+	if _, err := v.Parse(fresh); err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+	if doc := after.JWKS(); len(doc.Keys) != 1 {
+		t.Fatalf("HMAC secrets must not be published, got %+v", doc)
+	}
+
+	// Once HS256 is no longer allowed, the old tokens are refused.
+	strict := NewValidator(after, ValidatorConfig{Algorithms: []string{"EdDSA"}})
+	var ve *ValidationError
+	if _, err := strict.Parse(old); !errors.As(err, &ve) || ve.Code != CodeInvalidAlgorithm {
+		t.Fatalf("want %s with HS256 disallowed, got %v", CodeInvalidAlgorithm, err)
+	}
+
+	// Rotating the HMAC secret itself: the new one gets a kid, the old one keeps verifying.
+	rotated, err := NewKeySet(NewHMACKey("2025-02", "new-secret"), NewHMACKey("", "old-secret"))
+	if err != nil {
+		t.Fatalf("rotated keyset: %v", err)
+	}
+	if _, err := NewValidator(rotated, ValidatorConfig{}).Parse(old); err != nil {
+		t.Fatalf("token from before the HMAC rotation should still verify: %v", err)
+	}
+}
+
+func TestValidator_ErrorCodes(t *testing.T) {
+	ks, _ := NewKeySet(NewHMACKey("k1", "secret"))
+	v := NewValidator(ks, ValidatorConfig{Issuer: "iss-a", Audience: "aud-a", Leeway: time.Second})
+	now := time.Now()
+
+	// Issued by the validator itself → iss/aud/nbf stamped and accepted.
+	good, _ := v.Sign(jwt.MapClaims{"sub": 1, "exp": now.Add(time.Minute).Unix(), "iat": now.Unix()})
+	if _, err := v.Parse(good); err != nil {
+		t.Fatalf("expected valid token: %v", err)
+	}
+
+	other := NewValidator(ks, ValidatorConfig{Issuer: "iss-a", Audience: "aud-b"})
+	wrongAud, _ := other.Sign(jwt.MapClaims{"sub": 1, "exp": now.Add(time.Minute).Unix()})
+	expired, _ := v.Sign(jwt.MapClaims{"sub": 1, "exp": now.Add(-time.Minute).Unix()})
+	noneTok := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": 1})
+	none, _ := noneTok.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	cases := map[string]string{
+		"not-a-jwt": CodeMalformed,
+		expired:     CodeExpired,
+		wrongAud:    CodeInvalidAudience,
+		none:        CodeInvalidAlgorithm,
+	}
+	for raw, want := range cases {
+		_, err := v.Parse(raw)
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Code != want {
+			t.Fatalf("token %.20q: want %s, got %v", raw, want, err)
+		}
+	}
+}
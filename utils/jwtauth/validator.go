@@ -0,0 +1,125 @@
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Error codes returned to clients so they can tell "refresh me" from "log in again".
+const (
+	CodeMalformed        = "token_malformed"
+	CodeExpired          = "token_expired"
+	CodeNotYetValid      = "token_not_yet_valid"
+	CodeInvalidSignature = "token_invalid_signature"
+	CodeInvalidAlgorithm = "token_invalid_algorithm"
+	CodeInvalidIssuer    = "token_invalid_issuer"
+	CodeInvalidAudience  = "token_invalid_audience"
+	CodeInvalid          = "token_invalid"
+)
+
+// ValidationError wraps a parse/verify failure with a stable Code.
+type ValidationError struct {
+	Code string
+	Err  error
+}
+
+func (e *ValidationError) Error() string { return e.Code + ": " + e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// ValidatorConfig is the policy shared by issuance and verification.
+type ValidatorConfig struct {
+	Issuer     string        // "iss" stamped and required (empty disables)
+	Audience   string        // "aud" stamped and required (empty disables)
+	Leeway     time.Duration // clock skew tolerated on exp/nbf/iat
+	Algorithms []string      // accepted "alg" values; defaults to the algs of the key set
+}
+
+// Validator signs tokens with the standard claims it later insists on, so both sides cannot drift.
+// It implements Signer (for the service) and Parse (for middlewares.Auth).
+type Validator struct {
+	keys   *KeySet
+	cfg    ValidatorConfig
+	parser *jwt.Parser
+}
+
+// NewValidator binds a key set to a claim policy.
+func NewValidator(keys *KeySet, cfg ValidatorConfig) *Validator {
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = keys.Algorithms()
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(), // no immortal tokens
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &Validator{keys: keys, cfg: cfg, parser: jwt.NewParser(opts...)}
+}
+
+// Keys exposes the underlying key set (e.g. for the JWKS endpoint).
+func (v *Validator) Keys() *KeySet { return v.keys }
+
+// Sign stamps iss/aud/nbf (unless already set) and signs with the active key.
+func (v *Validator) Sign(claims jwt.Claims) (string, error) {
+	if mc, ok := claims.(jwt.MapClaims); ok {
+		if _, set := mc["iss"]; !set && v.cfg.Issuer != "" {
+			mc["iss"] = v.cfg.Issuer
+		}
+		if _, set := mc["aud"]; !set && v.cfg.Audience != "" {
+			mc["aud"] = v.cfg.Audience
+		}
+		if _, set := mc["nbf"]; !set {
+			mc["nbf"] = time.Now().Unix()
+		}
+	}
+	return v.keys.Sign(claims)
+}
+
+// Parse verifies signature, algorithm and standard claims; failures are *ValidationError.
+func (v *Validator) Parse(raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(raw, claims, v.keyfunc); err != nil {
+		return nil, &ValidationError{Code: classify(err), Err: err}
+	}
+	return claims, nil
+}
+
+// keyfunc enforces the allowed algorithm list before the key set picks a key by kid.
+func (v *Validator) keyfunc(t *jwt.Token) (any, error) {
+	alg := t.Method.Alg()
+	for _, a := range v.cfg.Algorithms {
+		if a == alg {
+			return v.keys.Keyfunc(t)
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", errAlg, alg)
+}
+
+// classify maps golang-jwt sentinel errors onto our codes.
+func classify(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return CodeMalformed
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return CodeExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return CodeNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return CodeInvalidAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return CodeInvalidIssuer
+	case errors.Is(err, errAlg):
+		return CodeInvalidAlgorithm
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return CodeInvalidSignature // bad signature or unknown kid
+	}
+	return CodeInvalid
+}
@@ -0,0 +1,42 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"HelmyTask/utils/logging"
+)
+
+func TestNew_JSONAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logging.New(&buf, logging.FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	l.With("component", "test").InfoContext(ctx, "hello", "n", 1)
+	l.DebugContext(ctx, "hidden") // Below the level.
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("want exactly one JSON line, got %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "hello" || rec["request_id"] != "req-1" || rec["component"] != "test" {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	if l, err := logging.ParseLevel("WARN"); err != nil || l != slog.LevelWarn {
+		t.Fatalf("ParseLevel(WARN) = %v, %v", l, err)
+	}
+	if _, err := logging.ParseLevel("loud"); err == nil {
+		t.Fatal("want error for unknown level")
+	}
+	if _, err := logging.New(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Fatal("want error for unknown format")
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mailer delivers a plain-text message. Swap the implementation (SMTP, API, ...) without touching the service.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer writes messages to the standard logger. Handy for local dev.
+// Bodies carry live reset/verification tokens, so they are only printed when showBody is set.
+type LogMailer struct{ showBody bool }
+
+// NewLogMailer returns a Mailer that only logs; showBody should be true in dev only.
+func NewLogMailer(showBody bool) *LogMailer { return &LogMailer{showBody: showBody} }
+
+// Send prints the message instead of delivering it.
+func (m LogMailer) Send(to, subject, body string) error {
+	if !m.showBody {
+		log.Printf("[mail] to=%s subject=%q (body withheld, %d bytes)", to, subject, len(body))
+		return nil
+	}
+	log.Printf("[mail] to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+// FileMailer appends messages to a file so tests/dev tools can read them back.
+type FileMailer struct {
+	path string
+	mu   sync.Mutex // serialize appends from concurrent requests
+}
+
+// NewFileMailer returns a Mailer that appends to path (created if missing).
+func NewFileMailer(path string) *FileMailer { return &FileMailer{path: path} }
+
+// Send appends one message block to the file.
+func (m *FileMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC3339), to, subject, body)
+	return err
+}
+
+// New picks an implementation by name ("log" or "file"); unknown names fall back to log.
+// showBody lets the log mailer print message bodies (dev only: they contain tokens).
+func New(kind, path string, showBody bool) Mailer {
+	if kind == "file" {
+		return NewFileMailer(path)
+	}
+	return NewLogMailer(showBody)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormPlugin times every GORM statement into db_query_duration_seconds.
+// Enable with db.Use(metrics.GormPlugin{}).
+type GormPlugin struct{}
+
+const startKey = "metrics:start"
+
+// Name implements gorm.Plugin.
+func (GormPlugin) Name() string { return "metrics" }
+
+// Initialize implements gorm.Plugin: hooks around each callback chain.
+// (gorm's processor type is unexported, hence one line per chain.)
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", start),
+		cb.Create().After("gorm:create").Register("metrics:after_create", finish("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", start),
+		cb.Query().After("gorm:query").Register("metrics:after_query", finish("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", start),
+		cb.Update().After("gorm:update").Register("metrics:after_update", finish("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", start),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", finish("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", start),
+		cb.Row().After("gorm:row").Register("metrics:after_row", finish("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", start),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", finish("raw")),
+	)
+}
+
+func start(tx *gorm.DB) { tx.InstanceSet(startKey, time.Now()) }
+
+func finish(op string) func(*gorm.DB) {
+	return func(tx *gorm.DB) { observe(op, tx) }
+}
+
+// observe reads the start time stored by the before hook.
+func observe(op string, tx *gorm.DB) {
+	v, ok := tx.InstanceGet(startKey)
+	if !ok {
+		return
+	}
+	start, _ := v.(time.Time)
+	table := tx.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+	failed := tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) // Not-found is a normal answer.
+	ObserveDB(op, table, failed, time.Since(start))
+}
@@ -0,0 +1,106 @@
+// Package metrics owns the Prometheus registry and every collector the app exports.
+// Other layers only call the small helpers below, so they never touch client_golang directly.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry is private to the app (not prometheus.DefaultRegisterer) so tests and
+// libraries cannot leak unrelated collectors into /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "GORM statement latency by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "error"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Cache lookups by cache name and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+
+	loginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_attempts_total",
+		Help: "Login attempts by outcome.",
+	}, []string{"result"})
+
+	logEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redislog_entries_total",
+		Help: "Redis log entries by result (written, dropped, failed).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(), // goroutines, GC, memstats
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}), // CPU, RSS, open fds
+		httpRequests, httpDuration, dbQueryDuration, cacheRequests, loginAttempts, logEntries,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTP records one finished request. route must be the template ("/users/:id"), not the raw path,
+// or every ID would become its own time series.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// ObserveDB records one GORM statement.
+func ObserveDB(operation, table string, failed bool, d time.Duration) {
+	dbQueryDuration.WithLabelValues(operation, table, strconv.FormatBool(failed)).Observe(d.Seconds())
+}
+
+// Cache results for CacheResult.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// CacheResult counts one lookup in the named cache.
+func CacheResult(cache, result string) {
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// LoginResult counts one login attempt by outcome (success, invalid_credentials, locked, ...).
+func LoginResult(result string) {
+	loginAttempts.WithLabelValues(result).Inc()
+}
+
+// Log entry results for LogEntries.
+const (
+	LogWritten = "written"
+	LogDropped = "dropped" // queue full or logger closed
+	LogFailed  = "failed"  // batch write error
+)
+
+// LogEntries counts n Redis log entries by result.
+func LogEntries(result string, n int) {
+	logEntries.WithLabelValues(result).Add(float64(n))
+}
@@ -0,0 +1,182 @@
+package redislog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EnsureGroup creates a consumer group on a log stream (and the stream itself) if missing.
+// A new group starts at the beginning of the stream, so entries written before it existed are drained too.
+func EnsureGroup(ctx context.Context, rdb *redis.Client, stream, group string) error {
+	err := rdb.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") { // Already there.
+		return nil
+	}
+	return err
+}
+
+// StreamMessage is one drained stream entry with its ID.
+type StreamMessage struct {
+	ID string
+	Entry
+}
+
+// entryFromStream decodes the fields written by StreamSink.
+func entryFromStream(m redis.XMessage) StreamMessage {
+	sm := StreamMessage{ID: m.ID}
+	sm.Level, _ = m.Values["level"].(string)
+	sm.Msg, _ = m.Values["msg"].(string)
+	sm.Time, _ = m.Values["time"].(string)
+	if meta, ok := m.Values["meta"].(string); ok {
+		_ = json.Unmarshal([]byte(meta), &sm.Meta)
+	}
+	return sm
+}
+
+// Consumer drains a log stream as one member of a consumer group. Entries stay pending in the
+// group until the handler succeeds and they are acknowledged, so a crash or handler error means
+// redelivery rather than loss. Entries trimmed by MAXLEN before they were read are gone, though.
+type Consumer struct {
+	rdb    *redis.Client
+	stream string
+	group  string
+	name   string // consumer name, unique per process (e.g. hostname)
+
+	count     int64         // entries per read
+	block     time.Duration // how long a read waits for new entries
+	claimIdle time.Duration // take over entries another consumer left pending this long; 0 = never
+	retry     time.Duration // pause after a handler error
+}
+
+// ConsumerOption customizes a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithReadCount sets how many entries one read returns at most (default 100).
+func WithReadCount(n int64) ConsumerOption { return func(c *Consumer) { c.count = n } }
+
+// WithBlock sets how long a read waits for new entries (default 5s).
+func WithBlock(d time.Duration) ConsumerOption { return func(c *Consumer) { c.block = d } }
+
+// WithClaimIdle takes over entries left pending by a dead consumer after d (default 1m; 0 disables).
+func WithClaimIdle(d time.Duration) ConsumerOption { return func(c *Consumer) { c.claimIdle = d } }
+
+// WithRetryDelay sets the pause before redelivering after a handler error (default 1s).
+func WithRetryDelay(d time.Duration) ConsumerOption { return func(c *Consumer) { c.retry = d } }
+
+// NewConsumer reads stream as consumer name in group.
+func NewConsumer(rdb *redis.Client, stream, group, name string, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		rdb: rdb, stream: stream, group: group, name: name,
+		count: 100, block: 5 * time.Second, claimIdle: time.Minute, retry: time.Second,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.count <= 0 {
+		c.count = 1
+	}
+	if c.block <= 0 {
+		c.block = time.Millisecond // 0 would make Redis block forever and ignore ctx.
+	}
+	return c
+}
+
+// Run hands batches to handle and acknowledges them once it returns nil, until ctx ends
+// (then it returns nil) or Redis fails. It first re-reads this consumer's own unacknowledged
+// entries from an earlier run; after a handler error the same batch is offered again.
+func (c *Consumer) Run(ctx context.Context, handle func(context.Context, []StreamMessage) error) error {
+	if err := EnsureGroup(ctx, c.rdb, c.stream, c.group); err != nil {
+		return err
+	}
+	pending := true // Our own backlog first.
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		var (
+			msgs []redis.XMessage
+			err  error
+		)
+		switch {
+		case pending:
+			msgs, err = c.read(ctx, "0")
+		case c.claimIdle > 0 && time.Since(lastClaim) >= c.claimIdle:
+			lastClaim = time.Now()
+			msgs, _, err = c.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream: c.stream, Group: c.group, Consumer: c.name,
+				MinIdle: c.claimIdle, Start: "0-0", Count: c.count,
+			}).Result()
+		default:
+			msgs, err = c.read(ctx, ">")
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if len(msgs) == 0 {
+			pending = false
+			continue
+		}
+
+		ids := make([]string, len(msgs))
+		batch := make([]StreamMessage, 0, len(msgs))
+		for i, m := range msgs {
+			ids[i] = m.ID
+			if len(m.Values) > 0 { // Empty when the entry was trimmed while pending; just ack it.
+				batch = append(batch, entryFromStream(m))
+			}
+		}
+		if len(batch) > 0 {
+			if err := handle(ctx, batch); err != nil {
+				pending = true // Still ours and unacknowledged: read it again.
+				select {
+				case <-ctx.Done():
+				case <-time.After(c.retry):
+				}
+				continue
+			}
+		}
+		if err := c.rdb.XAck(context.WithoutCancel(ctx), c.stream, c.group, ids...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read fetches from the group: "0" = this consumer's pending entries, ">" = never delivered ones.
+func (c *Consumer) read(ctx context.Context, id string) ([]redis.XMessage, error) {
+	args := &redis.XReadGroupArgs{
+		Group: c.group, Consumer: c.name, Streams: []string{c.stream, id}, Count: c.count, Block: c.block,
+	}
+	if id != ">" {
+		args.Block = -1 // History reads answer immediately; omit BLOCK.
+	}
+	res, err := c.rdb.XReadGroup(ctx, args).Result()
+	if errors.Is(err, redis.Nil) { // Block timed out.
+		return nil, nil
+	}
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	return res[0].Messages, nil
+}
+
+// FileHandler returns a Run handler that appends drained entries to s as JSON lines.
+func FileHandler(s *FileSink) func(context.Context, []StreamMessage) error {
+	return func(ctx context.Context, batch []StreamMessage) error {
+		recs := make([]Record, len(batch))
+		for i, m := range batch {
+			b, err := json.Marshal(m.Entry)
+			if err != nil {
+				return err
+			}
+			recs[i] = Record{Entry: m.Entry, JSON: b}
+		}
+		return s.Write(ctx, recs)
+	}
+}
@@ -0,0 +1,59 @@
+package redislog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConsumer_DrainsToFileAndAcks(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	ctx := context.Background()
+	if err := EnsureGroup(ctx, rdb, "stream", "shipper"); err != nil {
+		t.Fatalf("group: %v", err)
+	}
+	if err := EnsureGroup(ctx, rdb, "stream", "shipper"); err != nil { // Idempotent.
+		t.Fatalf("group again: %v", err)
+	}
+	l := New(nil, "", 0, 0, WithBufferSize(0), WithSink(NewStreamSink(rdb, "stream", 100), ""))
+	l.Info("one", map[string]string{"user_id": "1"})
+	l.Warn("two", nil)
+
+	path := filepath.Join(t.TempDir(), "drained.log")
+	file, err := NewFileSink(path, 0, 0)
+	if err != nil {
+		t.Fatalf("file: %v", err)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var calls atomic.Int32
+	writeFile := FileHandler(file)
+	handle := func(ctx context.Context, batch []StreamMessage) error {
+		if calls.Add(1) == 1 {
+			return errors.New("shipper down") // First delivery fails; must be redelivered.
+		}
+		err := writeFile(ctx, batch)
+		cancel() // Everything arrives in one batch.
+		return err
+	}
+	c := NewConsumer(rdb, "stream", "shipper", "c1", WithBlock(10*time.Millisecond), WithRetryDelay(time.Millisecond))
+	if err := c.Run(runCtx, handle); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	_ = file.Close()
+
+	b, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"one"`) || !strings.Contains(lines[0], `"user_id":"1"`) {
+		t.Fatalf("unexpected file contents %q", b)
+	}
+	pending, err := rdb.XPending(ctx, "stream", "shipper").Result()
+	if err != nil || pending.Count != 0 {
+		t.Fatalf("want everything acknowledged, got %+v (%v)", pending, err)
+	}
+}
@@ -0,0 +1,98 @@
+package redislog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink appends JSON lines to a local file and rotates it by size:
+// app.log → app.log.1 → app.log.2 ..., keeping at most maxBackups old files.
+type FileSink struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64 // rotate before a write would exceed this many bytes; 0 = never
+	maxBackups int   // rotated files kept; 0 = the old file is removed
+	f          *os.File
+	size       int64
+}
+
+// NewFileSink opens (or creates) path for appending; its directory is created if missing.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("redislog: file sink: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("redislog: file sink: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("redislog: file sink: %w", err)
+	}
+	s.f, s.size = f, st.Size()
+	return nil
+}
+
+// Write appends the batch, rotating first when it would push the file past maxSize.
+func (s *FileSink) Write(_ context.Context, batch []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	b := jsonLines(batch)
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// rotate shifts path.N-1 → path.N ... path → path.1 and starts an empty file.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("redislog: rotate: %w", err)
+	}
+	s.f = nil
+	if s.maxBackups <= 0 {
+		_ = os.Remove(s.path)
+	} else {
+		_ = os.Remove(s.backup(s.maxBackups)) // Oldest falls off.
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(s.backup(i), s.backup(i+1)) // Missing files are fine.
+		}
+		if err := os.Rename(s.path, s.backup(1)); err != nil {
+			return fmt.Errorf("redislog: rotate: %w", err)
+		}
+	}
+	return s.open()
+}
+
+func (s *FileSink) backup(i int) string { return fmt.Sprintf("%s.%d", s.path, i) }
+
+// Close flushes and closes the current file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
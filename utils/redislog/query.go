@@ -0,0 +1,134 @@
+package redislog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrDisabled is returned by Query and Tail when the logger has no Redis list sink.
+var ErrDisabled = errors.New("redislog: no redis list sink")
+
+// queryChunk is how many list items Query reads per LRANGE while looking for matches.
+const queryChunk = 200
+
+// Filter selects entries for Query and Tail. Zero fields match everything; set fields are ANDed.
+type Filter struct {
+	Levels   []string          // any of these levels
+	Contains string            // case-insensitive substring of the message
+	Since    time.Time         // entry time >= Since
+	Until    time.Time         // entry time <= Until
+	Meta     map[string]string // exact values of meta keys, e.g. {"user_id": "42"}
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if len(f.Levels) > 0 && !contains(f.Levels, e.Level) {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(strings.ToLower(e.Msg), strings.ToLower(f.Contains)) {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		t, err := time.Parse(time.RFC3339, e.Time)
+		if err != nil || (!f.Since.IsZero() && t.Before(f.Since)) || (!f.Until.IsZero() && t.After(f.Until)) {
+			return false
+		}
+	}
+	for k, v := range f.Meta {
+		if e.Meta[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Page is one slice of Query results, newest first.
+type Page struct {
+	Items      []Entry `json:"items"`
+	NextOffset int64   `json:"next_offset,omitempty"` // pass back as offset for older entries; absent at the end
+}
+
+// Query returns up to limit matching entries, scanning the list sink from offset (0 = newest).
+// Offsets are list positions, so entries written in between shift later pages by that many.
+func (l *Logger) Query(ctx context.Context, f Filter, offset int64, limit int) (Page, error) {
+	if l == nil || l.list == nil {
+		return Page{}, ErrDisabled
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	page := Page{Items: []Entry{}}
+	for pos := offset; ; pos += queryChunk {
+		raw, err := l.list.rdb.LRange(ctx, l.list.key, pos, pos+queryChunk-1).Result()
+		if err != nil {
+			return Page{}, err
+		}
+		for i, s := range raw {
+			var e Entry
+			if json.Unmarshal([]byte(s), &e) != nil || !f.Match(e) {
+				continue
+			}
+			page.Items = append(page.Items, e)
+			if len(page.Items) == limit {
+				if i < len(raw)-1 || len(raw) == queryChunk { // Possibly more after this one.
+					page.NextOffset = pos + int64(i) + 1
+				}
+				return page, nil
+			}
+		}
+		if len(raw) < queryChunk { // Reached the oldest entry.
+			return page, nil
+		}
+	}
+}
+
+// Tail streams entries written to the list sink from now on (by any instance sharing the key) until ctx ends,
+// then closes the channel. Entries that fail f are skipped.
+func (l *Logger) Tail(ctx context.Context, f Filter) (<-chan Entry, error) {
+	if l == nil || l.list == nil {
+		return nil, ErrDisabled
+	}
+	sub := l.list.rdb.Subscribe(ctx, l.list.tailChannel())
+	if _, err := sub.Receive(ctx); err != nil { // Wait for the subscription so nothing written after return is missed.
+		_ = sub.Close()
+		return nil, err
+	}
+	out := make(chan Entry, 64)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var e Entry
+				if json.Unmarshal([]byte(m.Payload), &e) != nil || !f.Match(e) {
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
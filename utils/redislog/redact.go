@@ -0,0 +1,111 @@
+package redislog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// RedactMode says how sensitive values are rewritten before an entry leaves the process.
+type RedactMode int
+
+const (
+	RedactMask RedactMode = iota // "j***@example.com", "203.0.113.***", "***"
+	RedactHash                   // "h:<hex>": keyed hash, so one user's entries still correlate
+	RedactOff                    // values stored as logged; dev only
+)
+
+// ParseRedactMode accepts "mask", "hash" or "off".
+func ParseRedactMode(s string) (RedactMode, error) {
+	switch s {
+	case "mask", "":
+		return RedactMask, nil
+	case "hash":
+		return RedactHash, nil
+	case "off":
+		return RedactOff, nil
+	}
+	return RedactMask, fmt.Errorf("unknown redislog redaction %q (want mask|hash|off)", s)
+}
+
+// DefaultRedactKeys are the meta keys treated as personal or secret data.
+var DefaultRedactKeys = []string{"email", "token", "password", "ip"}
+
+// emailPattern finds addresses in free text (messages, error strings).
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Redactor rewrites sensitive meta values and email addresses found in messages.
+// A meta key is sensitive when it equals one of keys or ends in "_<key>" (e.g. "refresh_token", "client_ip"),
+// ignoring case; other meta values are still scanned for email addresses, and bare IPs are redacted too.
+type Redactor struct {
+	mode   RedactMode
+	keys   []string
+	secret []byte // HMAC key for RedactHash
+}
+
+// NewRedactor builds a redactor; secret keys the hashes in RedactHash mode so they cannot be
+// reversed by hashing a list of known addresses.
+func NewRedactor(mode RedactMode, keys []string, secret []byte) *Redactor {
+	lk := make([]string, len(keys))
+	for i, k := range keys {
+		lk[i] = strings.ToLower(k)
+	}
+	return &Redactor{mode: mode, keys: lk, secret: secret}
+}
+
+// sensitive reports whether meta key k holds a value that must not be stored as is.
+func (r *Redactor) sensitive(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range r.keys {
+		if k == s || strings.HasSuffix(k, "_"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text replaces every email address in s.
+func (r *Redactor) Text(s string) string {
+	if r == nil || r.mode == RedactOff || !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllStringFunc(s, r.value)
+}
+
+// Meta returns a redacted copy of meta (meta itself is left alone; callers may reuse it).
+func (r *Redactor) Meta(meta map[string]string) map[string]string {
+	if r == nil || r.mode == RedactOff || len(meta) == 0 {
+		return meta
+	}
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		if r.sensitive(k) && v != "" {
+			out[k] = r.value(v)
+		} else if net.ParseIP(v) != nil { // A bare IP is personal data under any key.
+			out[k] = r.value(v)
+		} else {
+			out[k] = r.Text(v)
+		}
+	}
+	return out
+}
+
+// value rewrites one sensitive value according to the mode.
+func (r *Redactor) value(v string) string {
+	if r.mode == RedactHash {
+		m := hmac.New(sha256.New, r.secret)
+		m.Write([]byte(strings.ToLower(v))) // Same address, same hash regardless of case.
+		return "h:" + hex.EncodeToString(m.Sum(nil))[:16]
+	}
+	if local, domain, ok := strings.Cut(v, "@"); ok && local != "" {
+		return local[:1] + "***@" + domain // Domain kept: useful, rarely identifying.
+	}
+	if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+		return v[:strings.LastIndexByte(v, '.')+1] + "***"
+	}
+	return "***"
+}
@@ -0,0 +1,69 @@
+package redislog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_Mask(t *testing.T) {
+	r := NewRedactor(RedactMask, DefaultRedactKeys, nil)
+	in := map[string]string{
+		"email":         "jane@example.com",
+		"client_ip":     "203.0.113.7",
+		"refresh_token": "abc123",
+		"user_id":       "42",
+		"id":            "198.51.100.23",
+		"err":           "duplicate key jane@example.com",
+	}
+	got := r.Meta(in)
+	want := map[string]string{
+		"email":         "j***@example.com",
+		"client_ip":     "203.0.113.***",
+		"refresh_token": "***",
+		"user_id":       "42",
+		"id":            "198.51.100.***", // IP under a key that is not on the list
+		"err":           "duplicate key j***@example.com",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: want %q, got %q", k, v, got[k])
+		}
+	}
+	if in["email"] != "jane@example.com" {
+		t.Fatal("caller's map must not be modified")
+	}
+	if msg := r.Text("reset sent to Bob.Smith+x@mail.example.org"); msg != "reset sent to B***@mail.example.org" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestRedactor_HashCorrelatesAndOff(t *testing.T) {
+	r := NewRedactor(RedactHash, DefaultRedactKeys, []byte("secret"))
+	a, b := r.Meta(map[string]string{"email": "Jane@example.com"}), r.Meta(map[string]string{"email": "jane@example.com"})
+	if !strings.HasPrefix(a["email"], "h:") || a["email"] != b["email"] || strings.Contains(a["email"], "jane") {
+		t.Fatalf("want the same opaque hash for one address, got %q and %q", a["email"], b["email"])
+	}
+	if other := NewRedactor(RedactHash, DefaultRedactKeys, []byte("other")).Meta(map[string]string{"email": "jane@example.com"}); other["email"] == a["email"] {
+		t.Fatal("want hashes keyed by the secret")
+	}
+
+	off := NewRedactor(RedactOff, DefaultRedactKeys, nil)
+	if got := off.Meta(map[string]string{"email": "jane@example.com"}); got["email"] != "jane@example.com" {
+		t.Fatalf("want raw value with redaction off, got %q", got["email"])
+	}
+	if _, err := ParseRedactMode("plain"); err == nil {
+		t.Fatal("want error for unknown mode")
+	}
+}
+
+func TestLogger_RedactsByDefault(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	l := New(rdb, "logs", 100, 0, WithBufferSize(0))
+	l.Warn("login wrong password for jane@example.com", map[string]string{"email": "jane@example.com", "user_id": "1"})
+
+	raw, _ := rdb.LIndex(context.Background(), "logs", 0).Result()
+	if strings.Contains(raw, "jane@") || !strings.Contains(raw, "j***@example.com") || !strings.Contains(raw, `"user_id":"1"`) {
+		t.Fatalf("want email masked in msg and meta, got %s", raw)
+	}
+}
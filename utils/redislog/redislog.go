@@ -3,9 +3,15 @@ package redislog
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"HelmyTask/utils/logging"
+	"HelmyTask/utils/metrics"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -17,24 +23,135 @@ type Entry struct {
 	Meta  map[string]string `json:"meta,omitempty"`
 }
 
-// Logger pushes logs to a Redis LIST (e.g., "logs:app") and trims to a max length.
+// Overflow decides what happens when the queue is full.
+type Overflow int
+
+const (
+	Drop  Overflow = iota // discard the entry and count it (never slows a request down)
+	Block                 // wait for room in the queue (never loses an entry while running)
+)
+
+// ParseOverflow accepts "drop" or "block".
+func ParseOverflow(s string) (Overflow, error) {
+	switch s {
+	case "drop", "":
+		return Drop, nil
+	case "block":
+		return Block, nil
+	}
+	return Drop, fmt.Errorf("unknown redislog overflow %q (want drop|block)", s)
+}
+
+// Stats are running totals since New. Written and Failed count deliveries, so an entry
+// sent to two sinks counts twice.
+type Stats struct {
+	Written uint64 // stored by a sink
+	Dropped uint64 // discarded before any sink: queue full (Drop) or logger closed
+	Failed  uint64 // lost because a sink's batch write failed
+}
+
+// sinkEntry is a destination with its minimum level rank.
+type sinkEntry struct {
+	Sink
+	min int
+}
+
+// Logger fans entries out to its sinks; by default one Redis LIST (e.g., "logs:app") trimmed to a max length.
+// Entries are queued and written by one background goroutine in batches.
 type Logger struct {
-	rdb       *redis.Client
-	key       string        // list key, e.g. "logs:app"
-	max       int64         // keep last N entries
-	retention time.Duration // optional expire for the list key
+	sinks []sinkEntry
+	list  *ListSink // first list sink; what Query and Tail read (nil = none)
+
+	bufferSize    int           // queue capacity; 0 = write inline (no goroutine)
+	batchSize     int           // entries per sink write
+	flushInterval time.Duration // longest an entry waits in a partial batch
+	overflow      Overflow
+	redact        *Redactor // applied to msg and meta before any sink sees them
+
+	queue  chan Record
+	mu     sync.RWMutex // guards closed against in-flight enqueues and inline writes
+	closed bool
+	stop   chan struct{} // closed by Close
+	done   chan struct{} // closed when the writer has flushed and exited
+
+	written, dropped, failed atomic.Uint64
 }
 
-// New creates a Redis logger using a LIST. You’ll see this key in your Redis Desktop Manager.
-func New(rdb *redis.Client, key string, max int64, retention time.Duration) *Logger {
-	return &Logger{rdb: rdb, key: key, max: max, retention: retention}
+// Option customizes a Logger.
+type Option func(*Logger)
+
+// WithBufferSize sets the queue capacity (default 1024). 0 writes every entry inline, as before.
+func WithBufferSize(n int) Option { return func(l *Logger) { l.bufferSize = n } }
+
+// WithBatchSize sets how many entries go into one sink write (default 100).
+func WithBatchSize(n int) Option { return func(l *Logger) { l.batchSize = n } }
+
+// WithFlushInterval sets how long a partial batch may wait (default 500ms).
+func WithFlushInterval(d time.Duration) Option { return func(l *Logger) { l.flushInterval = d } }
+
+// WithOverflow picks the full-queue policy (default Drop).
+func WithOverflow(o Overflow) Option { return func(l *Logger) { l.overflow = o } }
+
+// WithRedactor replaces the default redaction (mask DefaultRedactKeys and emails in messages).
+func WithRedactor(r *Redactor) Option { return func(l *Logger) { l.redact = r } }
+
+// WithSink adds a destination that gets entries at minLevel or above ("" = all).
+// Any WithSink replaces the default list sink built from New's arguments; repeat it to fan out.
+func WithSink(s Sink, minLevel string) Option {
+	return func(l *Logger) { l.sinks = append(l.sinks, sinkEntry{Sink: s, min: levelRank[minLevel]}) }
+}
+
+// New creates a logger writing to a Redis LIST. You’ll see this key in your Redis Desktop Manager.
+// With WithSink options the list arguments are ignored; with neither sinks nor rdb it is a no-op.
+// Call Close on shutdown so queued entries reach the sinks.
+func New(rdb *redis.Client, key string, max int64, retention time.Duration, opts ...Option) *Logger {
+	l := &Logger{
+		bufferSize: 1024, batchSize: 100, flushInterval: 500 * time.Millisecond, overflow: Drop,
+		redact: NewRedactor(RedactMask, DefaultRedactKeys, nil),
+		stop: make(chan struct{}), done: make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	if len(l.sinks) == 0 && rdb != nil {
+		l.sinks = []sinkEntry{{Sink: NewListSink(rdb, key, max, retention)}}
+	}
+	for _, s := range l.sinks {
+		if ls, ok := s.Sink.(*ListSink); ok {
+			l.list = ls
+			break
+		}
+	}
+	if l.batchSize <= 0 {
+		l.batchSize = 1
+	}
+	if l.flushInterval <= 0 {
+		l.flushInterval = 500 * time.Millisecond
+	}
+	if len(l.sinks) == 0 || l.bufferSize <= 0 {
+		close(l.done) // Nothing to wait for.
+		return l
+	}
+	l.queue = make(chan Record, l.bufferSize)
+	go l.run()
+	return l
 }
 
-// log pushes a log entry as JSON -> LPUSH; then LTRIM; then EXPIRE.
-func (l *Logger) log(level, msg string, meta map[string]string) {
-	if l == nil || l.rdb == nil {
+// log redacts and encodes an entry and queues it (or writes it inline without a queue).
+// A request ID in ctx is added to meta as "request_id".
+func (l *Logger) log(ctx context.Context, level, msg string, meta map[string]string) {
+	if l == nil || len(l.sinks) == 0 {
 		return // no-op if logger not initialized
 	}
+	msg, meta = l.redact.Text(msg), l.redact.Meta(meta)
+	if id := logging.RequestID(ctx); id != "" {
+		m := make(map[string]string, len(meta)+1) // Copy: callers may reuse their map.
+		for k, v := range meta {
+			m[k] = v
+		}
+		m["request_id"] = id
+		meta = m
+	}
 	en := Entry{
 		Level: level,
 		Msg:   msg,
@@ -42,22 +159,145 @@ func (l *Logger) log(level, msg string, meta map[string]string) {
 		Meta:  meta,
 	}
 	b, _ := json.Marshal(en)
-	ctx := context.Background()
-	_ = l.rdb.LPush(ctx, l.key, b).Err()
-	_ = l.rdb.LTrim(ctx, l.key, 0, l.max-1).Err()
-	if l.retention > 0 {
-		_ = l.rdb.Expire(ctx, l.key, l.retention).Err()
+	l.enqueue(context.WithoutCancel(ctx), Record{Entry: en, JSON: b}) // Still record the entry if the request was cancelled.
+}
+
+// enqueue applies the overflow policy, or writes inline without a queue. The read lock keeps
+// Close from finishing the drain while a send is in flight, so nothing is stranded in the queue.
+func (l *Logger) enqueue(ctx context.Context, r Record) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.count(&l.dropped, metrics.LogDropped, 1)
+		return
+	}
+	if l.queue == nil {
+		l.deliver(ctx, []Record{r})
+		return
+	}
+	if l.overflow == Block {
+		l.queue <- r // The writer keeps draining until Close, which waits for this lock.
+		return
+	}
+	select {
+	case l.queue <- r:
+	default:
+		l.count(&l.dropped, metrics.LogDropped, 1)
 	}
 }
 
+// run batches queued entries until Close, then drains what is left.
+func (l *Logger) run() {
+	defer close(l.done)
+	t := time.NewTicker(l.flushInterval)
+	defer t.Stop()
+	batch := make([]Record, 0, l.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			l.deliver(context.Background(), batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case r := <-l.queue:
+			if batch = append(batch, r); len(batch) >= l.batchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		case <-l.stop:
+			for {
+				select {
+				case r := <-l.queue:
+					if batch = append(batch, r); len(batch) >= l.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver hands the batch to every sink, leaving out entries below each sink's minimum level.
+// A failing sink does not stop the others.
+func (l *Logger) deliver(ctx context.Context, batch []Record) {
+	for _, s := range l.sinks {
+		sub := batch
+		if s.min > 0 {
+			sub = make([]Record, 0, len(batch))
+			for _, r := range batch {
+				if levelRank[r.Level] >= s.min {
+					sub = append(sub, r)
+				}
+			}
+		}
+		if len(sub) == 0 {
+			continue
+		}
+		if err := s.Write(ctx, sub); err != nil {
+			l.count(&l.failed, metrics.LogFailed, len(sub))
+			continue
+		}
+		l.count(&l.written, metrics.LogWritten, len(sub))
+	}
+}
+
+func (l *Logger) count(c *atomic.Uint64, result string, n int) {
+	c.Add(uint64(n))
+	metrics.LogEntries(result, n)
+}
+
+// Stats returns running totals; Dropped and Failed are entries that never reached a sink.
+func (l *Logger) Stats() Stats {
+	if l == nil {
+		return Stats{}
+	}
+	return Stats{Written: l.written.Load(), Dropped: l.dropped.Load(), Failed: l.failed.Load()}
+}
+
+// Close flushes pending entries and closes the sinks; call it once during shutdown, before
+// closing the Redis client. ctx bounds how long it may wait. Entries logged after Close are dropped.
+func (l *Logger) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.stop)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return fmt.Errorf("redislog: flush: %w", ctx.Err())
+	}
+	var errs []error
+	for _, s := range l.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Convenience helpers
 
 //Log severity = normal information (not an error, not a warning).
-func (l *Logger) Info(msg string, meta map[string]string)  { l.log("info", msg, meta) }
+func (l *Logger) Info(msg string, meta map[string]string)  { l.log(context.Background(), "info", msg, meta) }
+
 
+func (l *Logger) Warn(msg string, meta map[string]string)  { l.log(context.Background(), "warn", msg, meta) }
+func (l *Logger) Error(msg string, meta map[string]string) { l.log(context.Background(), "error", msg, meta) }
 
-func (l *Logger) Warn(msg string, meta map[string]string)  { l.log("warn", msg, meta) }
-func (l *Logger) Error(msg string, meta map[string]string) { l.log("error", msg, meta) }
+// Context variants: use these while serving a request so the entry carries its request_id.
+func (l *Logger) InfoContext(ctx context.Context, msg string, meta map[string]string)  { l.log(ctx, "info", msg, meta) }
+func (l *Logger) WarnContext(ctx context.Context, msg string, meta map[string]string)  { l.log(ctx, "warn", msg, meta) }
+func (l *Logger) ErrorContext(ctx context.Context, msg string, meta map[string]string) { l.log(ctx, "error", msg, meta) }
 
 // Formatted variants
 func (l *Logger) Infof(format string, meta map[string]string, args ...any)  { l.Info(fmt.Sprintf(format, args...), meta) }
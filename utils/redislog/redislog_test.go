@@ -0,0 +1,211 @@
+package redislog
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// gateHook holds every pipeline until gate is closed, simulating a slow Redis.
+type gateHook struct{ gate chan struct{} }
+
+func (gateHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+func (gateHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+func (h gateHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		<-h.gate
+		return next(ctx, cmds)
+	}
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return mr, rdb
+}
+
+// waitQueueEmpty waits until the writer has taken everything queued so far.
+func waitQueueEmpty(t *testing.T, l *Logger) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(l.queue) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("writer did not pick up the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLogger_BatchesAndFlushesOnClose(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	l := New(rdb, "logs", 100, time.Hour, WithBatchSize(50), WithFlushInterval(time.Hour))
+
+	for i := 0; i < 10; i++ {
+		l.Info("hello", nil)
+	}
+	l.Warn("last", nil)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	items, err := mr.List("logs")
+	if err != nil || len(items) != 11 {
+		t.Fatalf("want 11 entries after close, got %d (%v)", len(items), err)
+	}
+	if st := l.Stats(); st.Written != 11 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if mr.TTL("logs") == 0 {
+		t.Fatal("want retention applied")
+	}
+	l.Info("after close", nil) // Must not panic; counted as dropped.
+	if st := l.Stats(); st.Dropped != 1 {
+		t.Fatalf("want 1 dropped after close, got %+v", st)
+	}
+}
+
+func TestLogger_NewestFirstAndTrimmed(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	l := New(rdb, "logs", 3, 0, WithBatchSize(10), WithFlushInterval(time.Hour))
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		l.Info(m, nil)
+	}
+	_ = l.Close(context.Background())
+
+	items, _ := mr.List("logs")
+	if len(items) != 3 {
+		t.Fatalf("want list trimmed to 3, got %d", len(items))
+	}
+	raw, _ := rdb.LIndex(context.Background(), "logs", 0).Result()
+	if want := `"msg":"e"`; !strings.Contains(raw, want) {
+		t.Fatalf("want newest entry at head, got %s", raw)
+	}
+}
+
+func TestLogger_DropWhenFull(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	h := gateHook{gate: make(chan struct{})}
+	rdb.AddHook(h)
+	l := New(rdb, "logs", 100, 0, WithBufferSize(2), WithBatchSize(1), WithOverflow(Drop))
+
+	l.Info("in flight", nil) // Taken by the writer, which then blocks in the hook.
+	waitQueueEmpty(t, l)
+	l.Info("queued 1", nil)
+	l.Info("queued 2", nil)
+	l.Info("dropped", nil) // Queue full.
+	if st := l.Stats(); st.Dropped != 1 {
+		t.Fatalf("want 1 dropped, got %+v", st)
+	}
+
+	close(h.gate)
+	_ = l.Close(context.Background())
+	if st := l.Stats(); st.Written != 3 || st.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestLogger_BlockWaitsForRoom(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	h := gateHook{gate: make(chan struct{})}
+	rdb.AddHook(h)
+	l := New(rdb, "logs", 100, 0, WithBufferSize(1), WithBatchSize(1), WithOverflow(Block))
+
+	l.Info("in flight", nil)
+	waitQueueEmpty(t, l)
+	l.Info("queued", nil)
+	sent := make(chan struct{})
+	go func() {
+		l.Info("blocked", nil)
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("want the caller to wait while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(h.gate)
+	<-sent
+	_ = l.Close(context.Background())
+	if st := l.Stats(); st.Written != 3 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestLogger_FailedWritesAreCounted(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close() // Nothing listens here any more.
+	rdb := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { _ = rdb.Close() })
+	l := New(rdb, "logs", 100, 0, WithBatchSize(10))
+
+	l.Info("a", nil)
+	l.Info("b", nil)
+	_ = l.Close(context.Background())
+	if st := l.Stats(); st.Failed != 2 || st.Written != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	if o, err := ParseOverflow("block"); err != nil || o != Block {
+		t.Fatalf("ParseOverflow(block) = %v, %v", o, err)
+	}
+	if _, err := ParseOverflow("spill"); err == nil {
+		t.Fatal("want error for unknown policy")
+	}
+}
+
+func TestLogger_QueryTimeRange(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	l := New(rdb, "logs", 100, 0, WithBufferSize(0))
+	l.Info("now", nil)
+
+	now := time.Now()
+	if p, err := l.Query(context.Background(), Filter{Since: now.Add(-time.Minute), Until: now.Add(time.Minute)}, 0, 10); err != nil || len(p.Items) != 1 {
+		t.Fatalf("want the entry inside the range, got %+v (%v)", p, err)
+	}
+	if p, _ := l.Query(context.Background(), Filter{Since: now.Add(time.Minute)}, 0, 10); len(p.Items) != 0 {
+		t.Fatalf("want nothing after the entry, got %+v", p)
+	}
+	if _, err := New(nil, "logs", 100, 0).Query(context.Background(), Filter{}, 0, 10); err != ErrDisabled {
+		t.Fatalf("want ErrDisabled without redis, got %v", err)
+	}
+}
+
+func TestLogger_TailStreamsNewEntries(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	l := New(rdb, "logs", 100, 0, WithBufferSize(0))
+	l.Error("before tail", nil) // Not replayed.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, err := l.Tail(ctx, Filter{Levels: []string{"error"}})
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	l.Info("skipped by filter", nil)
+	l.Error("boom", map[string]string{"user_id": "7"})
+
+	select {
+	case e := <-entries:
+		if e.Msg != "boom" || e.Meta["user_id"] != "7" {
+			t.Fatalf("unexpected entry %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no entry streamed")
+	}
+	cancel()
+	for range entries { // Closed once ctx ends.
+	}
+}
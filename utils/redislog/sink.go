@@ -0,0 +1,163 @@
+package redislog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Levels in increasing severity.
+const (
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelRank = map[string]int{LevelInfo: 0, LevelWarn: 1, LevelError: 2}
+
+// ParseLevel checks a minimum level from config; empty means info (everything).
+func ParseLevel(s string) (string, error) {
+	if s == "" {
+		return LevelInfo, nil
+	}
+	if _, ok := levelRank[s]; !ok {
+		return "", fmt.Errorf("unknown redislog level %q (want info|warn|error)", s)
+	}
+	return s, nil
+}
+
+// Record is an entry together with its JSON encoding, shared by every sink.
+type Record struct {
+	Entry
+	JSON []byte
+}
+
+// Sink stores log records. Write receives one batch, oldest first; it may be called from
+// request goroutines when the logger has no queue, so implementations must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, batch []Record) error
+	Close() error // Called once by Logger.Close after the last Write.
+}
+
+// ListSink keeps the newest entries in a Redis LIST (LPUSH + LTRIM) and publishes them for Tail.
+// It is the sink Query and Tail read from.
+type ListSink struct {
+	rdb       *redis.Client
+	key       string        // list key, e.g. "logs:app"
+	max       int64         // keep last N entries
+	retention time.Duration // optional expire for the list key
+}
+
+// NewListSink writes to the list key, trimmed to max entries; retention > 0 expires an idle key.
+func NewListSink(rdb *redis.Client, key string, max int64, retention time.Duration) *ListSink {
+	return &ListSink{rdb: rdb, key: key, max: max, retention: retention}
+}
+
+// Write sends the batch in a single round trip: LPUSH all; then LTRIM; then EXPIRE;
+// then PUBLISH each entry for Tail subscribers.
+func (s *ListSink) Write(ctx context.Context, batch []Record) error {
+	vals := make([]any, len(batch))
+	for i, r := range batch {
+		vals[i] = r.JSON
+	}
+	_, err := s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.LPush(ctx, s.key, vals...) // Last value ends up first, so newest stays at the head.
+		p.LTrim(ctx, s.key, 0, s.max-1)
+		if s.retention > 0 {
+			p.Expire(ctx, s.key, s.retention)
+		}
+		for _, r := range batch {
+			p.Publish(ctx, s.tailChannel(), r.JSON) // Fire-and-forget; nobody listening costs nothing.
+		}
+		return nil
+	})
+	return err
+}
+
+// Close is a no-op; the Redis client belongs to the caller.
+func (s *ListSink) Close() error { return nil }
+
+// tailChannel is the pub/sub channel every write also publishes to, so Tail sees all instances.
+func (s *ListSink) tailChannel() string { return s.key + ":tail" }
+
+// StreamSink appends entries to a Redis Stream with XADD, capped near maxLen entries.
+// Fields: level, msg, time and meta (JSON object, omitted when empty).
+type StreamSink struct {
+	rdb    *redis.Client
+	key    string // stream key, e.g. "logs:stream"
+	maxLen int64  // approximate cap (MAXLEN ~); 0 = unbounded
+}
+
+// NewStreamSink writes to the stream key, trimmed to about maxLen entries.
+func NewStreamSink(rdb *redis.Client, key string, maxLen int64) *StreamSink {
+	return &StreamSink{rdb: rdb, key: key, maxLen: maxLen}
+}
+
+// Write pipelines one XADD per record.
+func (s *StreamSink) Write(ctx context.Context, batch []Record) error {
+	_, err := s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, r := range batch {
+			p.XAdd(ctx, &redis.XAddArgs{
+				Stream: s.key,
+				MaxLen: s.maxLen,
+				Approx: true, // Trim whole macro nodes; much cheaper than an exact cap.
+				Values: streamFields(r.Entry),
+			})
+		}
+		return nil
+	})
+	return err
+}
+
+// Close is a no-op; the Redis client belongs to the caller.
+func (s *StreamSink) Close() error { return nil }
+
+func streamFields(e Entry) []any {
+	f := []any{"level", e.Level, "msg", e.Msg, "time", e.Time}
+	if len(e.Meta) > 0 {
+		m, _ := json.Marshal(e.Meta)
+		f = append(f, "meta", string(m))
+	}
+	return f
+}
+
+// WriterSink writes one JSON line per entry to w (stdout, a pipe, ...).
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink writes JSON lines to w.
+func NewWriterSink(w io.Writer) *WriterSink { return &WriterSink{w: w} }
+
+// NewStdoutSink writes JSON lines to standard output (for container log collectors).
+func NewStdoutSink() *WriterSink { return NewWriterSink(os.Stdout) }
+
+// Write emits the batch as JSON lines with a single write call.
+func (s *WriterSink) Write(_ context.Context, batch []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(jsonLines(batch))
+	return err
+}
+
+// Close is a no-op; the writer belongs to the caller.
+func (s *WriterSink) Close() error { return nil }
+
+func jsonLines(batch []Record) []byte {
+	n := 0
+	for _, r := range batch {
+		n += len(r.JSON) + 1
+	}
+	buf := make([]byte, 0, n)
+	for _, r := range batch {
+		buf = append(append(buf, r.JSON...), '\n')
+	}
+	return buf
+}
@@ -0,0 +1,85 @@
+package redislog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_FanOutWithMinLevel(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	var out bytes.Buffer
+	l := New(nil, "", 0, 0, WithBufferSize(0),
+		WithSink(NewListSink(rdb, "logs", 100, 0), ""),
+		WithSink(NewStreamSink(rdb, "stream", 100), LevelWarn),
+		WithSink(NewWriterSink(&out), LevelError),
+	)
+	l.Info("hello", nil)
+	l.Warn("careful", map[string]string{"user_id": "3"})
+	l.Error("boom", nil)
+	_ = l.Close(context.Background())
+
+	if items, _ := mr.List("logs"); len(items) != 3 {
+		t.Fatalf("want all 3 entries in the list, got %d", len(items))
+	}
+	msgs, err := rdb.XRange(context.Background(), "stream", "-", "+").Result()
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("want warn+error in the stream, got %d (%v)", len(msgs), err)
+	}
+	if v := msgs[0].Values; v["msg"] != "careful" || v["meta"] != `{"user_id":"3"}` {
+		t.Fatalf("unexpected stream fields %v", v)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"msg":"boom"`) {
+		t.Fatalf("want only the error on the writer, got %q", out.String())
+	}
+	if st := l.Stats(); st.Written != 6 {
+		t.Fatalf("want 6 deliveries, got %+v", st)
+	}
+	if p, err := l.Query(context.Background(), Filter{}, 0, 10); err != nil || len(p.Items) != 3 {
+		t.Fatalf("want Query to read the list sink, got %+v (%v)", p, err)
+	}
+}
+
+func TestLogger_NoSinksIsNoop(t *testing.T) {
+	l := New(nil, "logs", 100, time.Hour)
+	l.Info("nowhere", nil)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if st := l.Stats(); st != (Stats{}) {
+		t.Fatalf("want no activity, got %+v", st)
+	}
+}
+
+func TestFileSink_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	s, err := NewFileSink(path, 100, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	rec := Record{JSON: []byte(strings.Repeat("x", 59))} // 60 bytes with the newline: one per file.
+	for i := 0; i < 4; i++ {
+		if err := s.Write(context.Background(), []Record{rec}); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		if b, err := os.ReadFile(p); err != nil || len(b) != 60 {
+			t.Fatalf("%s: want one line, got %d bytes (%v)", p, len(b), err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("want only 2 backups kept, stat .3: %v", err)
+	}
+	if err := s.Write(context.Background(), []Record{rec}); err == nil {
+		t.Fatal("want an error writing after Close")
+	}
+}
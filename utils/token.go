@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// RandomToken returns n cryptographically random bytes encoded as URL-safe base64.
+// Used for opaque tokens (refresh, reset, ...) that are handed to clients.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil // No padding; safe in URLs and JSON.
+}
+
+// HashToken returns the hex SHA-256 of an opaque token.
+// We only ever store this digest so a leaked table/key does not leak usable tokens.
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
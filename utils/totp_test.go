@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors (SHA1 key "12345678901234567890"), truncated to 6 digits.
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		got, err := TOTPCode(secret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("code at %d: %v", ts, err)
+		}
+		if got != want {
+			t.Fatalf("code at %d: want %s, got %s", ts, want, got)
+		}
+	}
+	if !ValidateTOTP(secret, "287082", time.Unix(89, 0), 1) {
+		t.Fatalf("expected previous step to be accepted with skew 1")
+	}
+	if ValidateTOTP(secret, "287082", time.Unix(150, 0), 1) {
+		t.Fatalf("expected code two steps old to be rejected")
+	}
+}
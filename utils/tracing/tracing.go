@@ -0,0 +1,107 @@
+// Package tracing sets up OpenTelemetry: the tracer provider, the exporter and W3C propagation.
+// Instrumentation (Gin middleware, GORM plugin, Redis hook, service decorator) only needs Tracer().
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"HelmyTask/global" // Service version.
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Config.Exporter.
+const (
+	ExporterNone   = "none"   // no spans recorded (propagation still works)
+	ExporterStdout = "stdout" // pretty-printed JSON on stdout, for local debugging
+	ExporterOTLP   = "otlp"   // OTLP/HTTP to a collector, Jaeger, Tempo, ...
+)
+
+const instrumentation = "HelmyTask" // Instrumentation scope of every span we create.
+
+// propagator reads and writes W3C traceparent/tracestate and baggage headers.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Config selects the exporter and sampling.
+type Config struct {
+	ServiceName string  // service.name resource attribute
+	Environment string  // deployment.environment.name
+	Exporter    string  // ExporterNone | ExporterStdout | ExporterOTLP
+	Endpoint    string  // OTLP/HTTP URL, e.g. "http://localhost:4318"; empty = OTEL_EXPORTER_OTLP_* env or default
+	SampleRatio float64 // share of new traces to record (parents' decisions are honoured)
+}
+
+// Tracer returns the app tracer from the global provider (resolved per call, so tests can swap providers).
+func Tracer() trace.Tracer { return otel.Tracer(instrumentation) }
+
+// Init installs the configured exporter as the global provider and W3C trace-context propagation.
+// The returned func flushes pending spans; call it on shutdown.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterNone:
+		otel.SetTextMapPropagator(propagator) // Still forward incoming traceparent headers.
+		return func(context.Context) error { return nil }, nil // Global provider stays a no-op.
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q (want none|stdout|otlp)", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %s exporter: %w", cfg.Exporter, err)
+	}
+	tp := Install(exp, cfg, sdktrace.WithBatcher(exp))
+	return tp.Shutdown, nil
+}
+
+// Install makes a provider exporting to exp the global one and returns it.
+// Tests pass an in-memory exporter with sdktrace.WithSyncer(exp) so spans are visible immediately.
+func Install(exp sdktrace.SpanExporter, cfg Config, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	if len(opts) == 0 {
+		opts = []sdktrace.TracerProviderOption{sdktrace.WithSyncer(exp)}
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(global.AppVersion),
+		semconv.DeploymentEnvironmentNameKey.String(cfg.Environment),
+	)
+	tp := sdktrace.NewTracerProvider(append(opts,
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	return tp
+}
+
+// End finishes span, marking it failed when err is non-nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
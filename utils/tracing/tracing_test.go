@@ -0,0 +1,65 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"HelmyTask/utils/tracing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormAndRedisSpans_NestUnderCaller(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := tracing.Install(exp, tracing.Config{ServiceName: "test"})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	db, err := gorm.Open(sqlite.Open("file:tracing?mode=memory"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		t.Fatalf("plugin: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	tracing.InstrumentRedis(rdb)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+	if err := db.WithContext(ctx).Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var w widget
+	_ = db.WithContext(ctx).First(&w, 999).Error // Not found must not fail the span.
+	_ = rdb.Get(ctx, "missing").Err()            // Neither must redis.Nil.
+	parent.End()
+
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range exp.GetSpans() {
+		byName[s.Name] = s
+	}
+	for _, name := range []string{"db.create", "db.select", "redis.get"} {
+		s, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing span %q (have %v)", name, byName)
+		}
+		if s.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: not a child of the caller span", name)
+		}
+		if s.Status.Code.String() == "Error" {
+			t.Errorf("%s: unexpected error status %q", name, s.Status.Description)
+		}
+	}
+}